@@ -0,0 +1,237 @@
+// Command docker-it runs docker environments defined in a definition file outside of go test.
+package main
+
+import (
+	"flag"
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"github.com/grepplabs/docker-it/definition"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: docker-it [-f file] [-id id] <command> [args]
+
+Commands:
+  up                          create and start all components
+  down                        destroy all components
+  status                      show the component containers
+  logs <component>            print the log output of a component
+  exec <component> -- <cmd>   run a command in a component container
+  env                         print the component hosts and ports as export lines
+  prune [flags]               remove containers, networks and volumes left over by any environment,
+                              see docker-it prune -h
+
+Flags:
+`
+
+const idEnv = "DOCKER_IT_ID"
+
+type cli struct {
+	out        io.Writer
+	definition *definition.Definition
+	env        *dit.DockerEnvironment
+}
+
+func main() {
+	flags := flag.NewFlagSet("docker-it", flag.ExitOnError)
+	file := flags.String("f", "docker-it.yaml", "environment definition file")
+	id := flags.String("id", os.Getenv(idEnv), "environment ID, defaults to the definition file name (env "+idEnv+")")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *id == "" {
+		*id = defaultID(*file)
+	}
+	code, err := run(os.Stdout, *file, *id, flags.Arg(0), flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "docker-it:", err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func run(out io.Writer, file string, id string, command string, args []string) (int, error) {
+	// prune does not need a definition
+	if command == "prune" {
+		return 0, prune(out, args)
+	}
+	c, err := newCli(out, file, id)
+	if err != nil {
+		return 1, err
+	}
+	defer c.env.Close()
+
+	switch command {
+	case "up":
+		return 0, c.up()
+	case "down":
+		return 0, c.down()
+	case "status":
+		return 0, c.status()
+	case "logs":
+		if len(args) != 1 {
+			return 2, fmt.Errorf("logs requires a component name")
+		}
+		return 0, c.env.WriteLogs(args[0], out, os.Stderr)
+	case "exec":
+		if len(args) < 3 || args[1] != "--" {
+			return 2, fmt.Errorf("exec requires a component name and a command after --")
+		}
+		return c.env.Exec(args[0], args[2:], out, os.Stderr)
+	case "env":
+		return 0, c.exports()
+	}
+	return 2, fmt.Errorf("unknown command '%s'", command)
+}
+
+func newCli(out io.Writer, file string, id string) (*cli, error) {
+	d, err := definition.LoadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	// logs go to stderr, stdout is reserved for the command output e.g. eval $(docker-it env)
+	d.LogOutput = os.Stderr
+	components, err := d.DockerComponents()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	env, err := dit.NewDockerEnvironmentWithOptions([]dit.Option{
+		dit.WithID(id),
+		dit.WithLogger(log.New(os.Stderr, "", log.Ldate|log.Ltime)),
+	}, components...)
+	if err != nil {
+		return nil, err
+	}
+	// containers started by a previous invocation
+	if err := env.Attach(); err != nil {
+		env.Close()
+		return nil, err
+	}
+	return &cli{out: out, definition: d, env: env}, nil
+}
+
+func (r *cli) names() []string {
+	names := make([]string, 0, len(r.definition.Components))
+	for _, component := range r.definition.Components {
+		names = append(names, component.Name)
+	}
+	return names
+}
+
+func (r *cli) up() error {
+	if err := r.env.StartParallel(r.names()...); err != nil {
+		return err
+	}
+	return r.status()
+}
+
+func (r *cli) down() error {
+	return r.env.Destroy(r.names()...)
+}
+
+func (r *cli) status() error {
+	statuses, err := r.env.Status()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(r.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tCONTAINER\tSTATE\tPORTS")
+	for _, status := range statuses {
+		container, state := dit.TruncateID(status.ContainerID), status.State
+		if status.ContainerID == "" {
+			container, state = "-", "not created"
+		}
+		ports := make([]string, 0, len(status.Ports))
+		for _, port := range status.Ports {
+			ports = append(ports, fmt.Sprintf("%s:%d->%d", r.env.Host(), port.HostPort, port.ContainerPort))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Name, container, state, strings.Join(ports, ", "))
+	}
+	return w.Flush()
+}
+
+func (r *cli) exports() error {
+	statuses, err := r.env.Status()
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.ContainerID == "" {
+			return fmt.Errorf("DockerComponent [%s] container was not created, run up first", status.Name)
+		}
+	}
+	for _, component := range r.definition.Components {
+		fmt.Fprintf(r.out, "export %s=%s\n", exportName(component.Name, "HOST"), r.env.Host())
+		for i, port := range component.ExposedPorts {
+			hostPort, err := r.env.Port(component.Name, port.Name)
+			if err != nil {
+				return err
+			}
+			if port.Name == "" || i == 0 {
+				fmt.Fprintf(r.out, "export %s=%d\n", exportName(component.Name, "PORT"), hostPort)
+			}
+			if port.Name != "" {
+				fmt.Fprintf(r.out, "export %s=%d\n", exportName(component.Name, port.Name, "PORT"), hostPort)
+			}
+		}
+	}
+	return nil
+}
+
+func prune(out io.Writer, args []string) error {
+	flags := flag.NewFlagSet("prune", flag.ExitOnError)
+	var filter dit.PruneFilter
+	flags.StringVar(&filter.EnvironmentID, "env", "", "environment ID, all environments if empty")
+	flags.StringVar(&filter.ComponentName, "component", "", "component name, all components if empty")
+	flags.DurationVar(&filter.OlderThan, "older-than", 0, "minimal age of the resources e.g. 1h")
+	flags.BoolVar(&filter.DryRun, "dry-run", false, "list the resources without removing them")
+	flags.BoolVar(&filter.MatchNames, "match-names", false, "select also stopped containers without labels named <component>-<environment ID>, check them with -dry-run first")
+	flags.Parse(args)
+
+	resources, err := dit.Prune(filter)
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tNAME\tENVIRONMENT\tCOMPONENT\tCREATED")
+	for _, resource := range resources {
+		created := "-"
+		if !resource.Created.IsZero() {
+			created = resource.Created.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", resource.Type, resource.Name, resource.EnvironmentID, resource.ComponentName, created)
+	}
+	w.Flush()
+	if err == nil && !filter.DryRun {
+		fmt.Fprintf(out, "Removed %d resources\n", len(resources))
+	}
+	return err
+}
+
+var invalidExportChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// exportName provides shell variable name e.g. IT_REDIS_PORT
+func exportName(parts ...string) string {
+	return invalidExportChars.ReplaceAllString(strings.ToUpper(strings.Join(parts, "_")), "_")
+}
+
+var invalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// defaultID derives the environment ID from the definition file name
+func defaultID(file string) string {
+	name := filepath.Base(file)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	return strings.TrimLeft(invalidIDChars.ReplaceAllString(name, "-"), "_.-")
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExportName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("IT_REDIS_HOST", exportName("it-redis", "HOST"))
+	a.Equal("IT_KAFKA_ZOOKEEPER_PORT", exportName("it-kafka", "zookeeper", "PORT"))
+}
+
+func TestDefaultID(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("docker-it", defaultID("docker-it.yaml"))
+	a.Equal("my-env", defaultID("/tmp/.my env.json"))
+}
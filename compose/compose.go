@@ -0,0 +1,190 @@
+// Package compose loads docker environments from docker-compose files.
+package compose
+
+import (
+	"bytes"
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Options defines how a compose file is loaded
+type Options struct {
+	// Project name used as the environment ID. If not specified, the top-level name of the compose file
+	// or the name of the compose file directory is used.
+	ProjectName string
+	// Bind the published ports of the services. If not set, the host ports are allocated dynamically.
+	PublishedPorts bool
+}
+
+// Project holds the services of a compose file converted to docker components
+type Project struct {
+	// Normalized project name
+	Name string
+	// Docker components in the order of service dependencies
+	Components []dit.DockerComponent
+}
+
+// ServiceNames returns the names of the services in the order of their dependencies,
+// dependencies are started first by DockerEnvironment.Start
+func (r *Project) ServiceNames() []string {
+	names := make([]string, 0, len(r.Components))
+	for _, component := range r.Components {
+		names = append(names, component.Name)
+	}
+	return names
+}
+
+// NewDockerEnvironment creates a new docker test environment with the project name as ID
+func (r *Project) NewDockerEnvironment() (*dit.DockerEnvironment, error) {
+	return dit.NewDockerEnvironmentWithID(r.Name, r.Components...)
+}
+
+// LoadFile reads a compose file. Relative bind mounts are resolved against the compose file directory.
+func LoadFile(path string, options Options) (*Project, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	workingDir := filepath.Dir(absPath)
+	if options.ProjectName == "" {
+		options.ProjectName = filepath.Base(workingDir)
+	}
+	project, err := Load(bytes.NewReader(data), workingDir, options)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return project, nil
+}
+
+// Load reads a compose file. Relative bind mounts are resolved against the working directory.
+func Load(reader io.Reader, workingDir string, options Options) (*Project, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("compose file is empty")
+	}
+	document := root.Content[0]
+	if err := interpolate(document); err != nil {
+		return nil, err
+	}
+	if document.Kind != yaml.MappingNode {
+		return nil, lineError(document, "compose file must be a mapping")
+	}
+
+	var name string
+	var services, volumes *yaml.Node
+	err = forEachKey(document, "compose file", func(key string, value *yaml.Node) (bool, error) {
+		switch key {
+		case "version":
+		case "name":
+			name = value.Value
+		case "services":
+			services = value
+		case "volumes":
+			volumes = value
+		default:
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if options.ProjectName != "" {
+		name = options.ProjectName
+	}
+	projectName := dit.ComposeProjectName(name)
+	if projectName == "" {
+		return nil, fmt.Errorf("compose project name '%s' is invalid", name)
+	}
+	if services == nil || services.Kind != yaml.MappingNode || len(services.Content) == 0 {
+		return nil, lineError(document, "services must not be empty")
+	}
+	namedVolumes, err := parseVolumeNames(volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	converter := &converter{
+		projectName:    projectName,
+		workingDir:     workingDir,
+		namedVolumes:   namedVolumes,
+		publishedPorts: options.PublishedPorts,
+	}
+	parsed := make([]*service, 0)
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		service, err := converter.service(services.Content[i], services.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, service)
+	}
+	ordered, err := sortByDependencies(parsed)
+	if err != nil {
+		return nil, err
+	}
+	components := make([]dit.DockerComponent, 0, len(ordered))
+	for _, service := range ordered {
+		components = append(components, service.component)
+	}
+	return &Project{Name: projectName, Components: components}, nil
+}
+
+func parseVolumeNames(volumes *yaml.Node) (map[string]struct{}, error) {
+	names := make(map[string]struct{})
+	if volumes == nil || volumes.Tag == "!!null" {
+		return names, nil
+	}
+	if volumes.Kind != yaml.MappingNode {
+		return nil, lineError(volumes, "volumes must be a mapping")
+	}
+	for i := 0; i+1 < len(volumes.Content); i += 2 {
+		key, value := volumes.Content[i], volumes.Content[i+1]
+		if value.Kind == yaml.MappingNode && len(value.Content) != 0 {
+			return nil, lineError(value, fmt.Sprintf("volume '%s' options are not supported", key.Value))
+		}
+		names[key.Value] = struct{}{}
+	}
+	return names, nil
+}
+
+// forEachKey calls the function for each key of the mapping except extension fields (x-).
+// Keys not handled by the function are reported as not supported.
+func forEachKey(mapping *yaml.Node, context string, f func(key string, value *yaml.Node) (bool, error)) error {
+	if mapping.Kind != yaml.MappingNode {
+		return lineError(mapping, fmt.Sprintf("%s must be a mapping", context))
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key := mapping.Content[i]
+		if strings.HasPrefix(key.Value, "x-") {
+			continue
+		}
+		handled, err := f(key.Value, mapping.Content[i+1])
+		if err != nil {
+			return err
+		}
+		if !handled {
+			return lineError(key, fmt.Sprintf("%s key '%s' is not supported", context, key.Value))
+		}
+	}
+	return nil
+}
+
+func lineError(value *yaml.Node, message string) error {
+	return fmt.Errorf("line %d: %s", value.Line, message)
+}
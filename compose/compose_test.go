@@ -0,0 +1,146 @@
+package compose
+
+import (
+	dit "github.com/grepplabs/docker-it"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	a := assert.New(t)
+
+	project, err := LoadFile("testdata/docker-compose.yml", Options{})
+	a.Nil(err)
+	a.Equal("testdata", project.Name)
+	a.Equal([]string{"db", "web"}, project.ServiceNames())
+
+	workingDir, err := filepath.Abs("testdata")
+	a.Nil(err)
+
+	db := project.Components[0]
+	a.Equal("postgres:9.6", db.Image)
+	a.Equal(map[string]string{"POSTGRES_PASSWORD": "postgres", "POSTGRES_DB": "app"}, db.EnvironmentVariables)
+	a.Equal([]dit.Port{{ContainerPort: 5432}}, db.ExposedPorts)
+	a.Equal([]string{"testdata_pgdata:/var/lib/postgresql/data"}, db.Binds)
+	a.Equal(&dit.Healthcheck{
+		Test:        []string{"CMD", "pg_isready", "-U", "postgres"},
+		Interval:    time.Second,
+		Timeout:     5 * time.Second,
+		Retries:     30,
+		StartPeriod: 10 * time.Second,
+	}, db.Healthcheck)
+
+	web := project.Components[1]
+	a.Equal("nginx:1.13", web.Image)
+	a.Equal([]dit.Port{{ContainerPort: 80}, {Name: "443", ContainerPort: 443}}, web.ExposedPorts)
+	a.Equal([]string{"nginx", "-g", "daemon off;"}, web.Cmd)
+	a.Equal([]string{filepath.Join(workingDir, "html") + ":/usr/share/nginx/html:ro"}, web.Binds)
+}
+
+func TestLoadFileOptions(t *testing.T) {
+	a := assert.New(t)
+
+	project, err := LoadFile("testdata/docker-compose.yml", Options{ProjectName: "My.Project", PublishedPorts: true})
+	a.Nil(err)
+	a.Equal("myproject", project.Name)
+	a.Equal([]dit.Port{{ContainerPort: 80, HostPort: 18080}, {Name: "443", ContainerPort: 443, HostPort: 18443}}, project.Components[1].ExposedPorts)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		compose string
+		err     string
+	}{
+		{
+			name:    "unsupported service key",
+			compose: "services:\n  web:\n    image: nginx\n    build: .\n",
+			err:     "line 4: service 'web' key 'build' is not supported",
+		},
+		{
+			name:    "unsupported top-level key",
+			compose: "services:\n  web:\n    image: nginx\nnetworks:\n  default:\n",
+			err:     "line 4: compose file key 'networks' is not supported",
+		},
+		{
+			name:    "missing image",
+			compose: "services:\n  web:\n    command: run\n",
+			err:     "line 2: service 'web' image must be defined",
+		},
+		{
+			name:    "udp port",
+			compose: "services:\n  web:\n    image: nginx\n    ports:\n      - 53/udp\n",
+			err:     "line 5: service 'web' port '53/udp' protocol 'udp' is not supported",
+		},
+		{
+			name:    "undefined volume",
+			compose: "services:\n  web:\n    image: nginx\n    volumes:\n      - data:/data\n",
+			err:     "line 5: service 'web' volume 'data' is not defined in the top-level volumes",
+		},
+		{
+			name:    "undefined dependency",
+			compose: "services:\n  web:\n    image: nginx\n    depends_on: [db]\n",
+			err:     "line 2: service 'web' depends on undefined service 'db'",
+		},
+		{
+			name:    "dependency cycle",
+			compose: "services:\n  a:\n    image: nginx\n    depends_on: [b]\n  b:\n    image: nginx\n    depends_on: [a]\n",
+			err:     "line 2: dependency cycle between services a -> b -> a",
+		},
+		{
+			name:    "required variable",
+			compose: "services:\n  web:\n    image: nginx:${DOCKER_IT_UNDEFINED_VERSION:?version is required}\n",
+			err:     "line 3: required variable DOCKER_IT_UNDEFINED_VERSION is missing a value: version is required",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := assert.New(t)
+			_, err := Load(strings.NewReader(test.compose), "/tmp", Options{ProjectName: "test"})
+			a.EqualError(err, test.err)
+		})
+	}
+}
+
+func TestInterpolateString(t *testing.T) {
+	a := assert.New(t)
+	lookupEnv := func(name string) (string, bool) {
+		switch name {
+		case "VERSION":
+			return "1.0", true
+		case "EMPTY":
+			return "", true
+		}
+		return "", false
+	}
+	tests := map[string]string{
+		"app:${VERSION}":         "app:1.0",
+		"app:$VERSION":           "app:1.0",
+		"app:${MISSING:-latest}": "app:latest",
+		"app:${EMPTY:-latest}":   "app:latest",
+		"app:${EMPTY-latest}":    "app:",
+		"app:${MISSING}":         "app:",
+		"$$HOME":                 "$HOME",
+	}
+	for input, expected := range tests {
+		result, err := interpolateString(input, lookupEnv)
+		a.Nil(err)
+		a.Equal(expected, result, input)
+	}
+	_, err := interpolateString("${VERSION", lookupEnv)
+	a.EqualError(err, "invalid interpolation format in '${VERSION'")
+}
+
+func TestSplitCommand(t *testing.T) {
+	a := assert.New(t)
+
+	cmd, err := splitCommand(`server -dev 'a b' "c \"d\"" e\ f`)
+	a.Nil(err)
+	a.Equal([]string{"server", "-dev", "a b", `c "d"`, "e f"}, cmd)
+
+	_, err = splitCommand(`echo "a`)
+	a.EqualError(err, `'echo "a' is not terminated`)
+}
@@ -0,0 +1,52 @@
+package compose
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sortByDependencies orders the services so that dependencies precede their dependents.
+// Otherwise the order of the compose file is kept.
+func sortByDependencies(services []*service) ([]*service, error) {
+	byName := make(map[string]*service)
+	for _, service := range services {
+		byName[service.component.Name] = service
+	}
+	for _, service := range services {
+		for _, dependency := range service.dependsOn {
+			if _, ok := byName[dependency]; !ok {
+				return nil, fmt.Errorf("line %d: service '%s' depends on undefined service '%s'", service.line, service.component.Name, dependency)
+			}
+		}
+	}
+
+	ordered := make([]*service, 0, len(services))
+	visited := make(map[string]bool)
+	var visit func(service *service, path []string) error
+	visit = func(service *service, path []string) error {
+		name := service.component.Name
+		for i, visiting := range path {
+			if visiting == name {
+				return fmt.Errorf("line %d: dependency cycle between services %s", service.line, strings.Join(append(path[i:], name), " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, dependency := range service.dependsOn {
+			if err := visit(byName[dependency], path); err != nil {
+				return err
+			}
+		}
+		visited[name] = true
+		ordered = append(ordered, service)
+		return nil
+	}
+	for _, service := range services {
+		if err := visit(service, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
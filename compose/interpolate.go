@@ -0,0 +1,72 @@
+package compose
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// $$, ${NAME}, ${NAME:-default}, ${NAME-default}, ${NAME:?error}, ${NAME?error} or $NAME
+var variablePattern = regexp.MustCompile(`\$(?:(\$)|\{([a-zA-Z_][a-zA-Z0-9_]*)(?:(:?[-?])([^}]*))?\}|([a-zA-Z_][a-zA-Z0-9_]*))`)
+
+// interpolate substitutes environment variables in all scalar values
+func interpolate(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if !strings.Contains(value.Value, "$") {
+			return nil
+		}
+		result, err := interpolateString(value.Value, os.LookupEnv)
+		if err != nil {
+			return lineError(value, err.Error())
+		}
+		value.Value = result
+		return nil
+	}
+	for _, child := range value.Content {
+		if err := interpolate(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func interpolateString(s string, lookupEnv func(string) (string, bool)) (string, error) {
+	var err error
+	result := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		groups := variablePattern.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return "$"
+		}
+		name, operator, argument := groups[2], groups[3], groups[4]
+		if name == "" {
+			name = groups[5]
+		}
+		value, ok := lookupEnv(name)
+		switch operator {
+		case ":-":
+			if value == "" {
+				return argument
+			}
+		case "-":
+			if !ok {
+				return argument
+			}
+		case ":?", "?":
+			if !ok || (operator == ":?" && value == "") {
+				if err == nil {
+					err = fmt.Errorf("required variable %s is missing a value: %s", name, argument)
+				}
+			}
+		}
+		return value
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(variablePattern.ReplaceAllString(s, ""), "${") {
+		return "", fmt.Errorf("invalid interpolation format in '%s'", s)
+	}
+	return result, nil
+}
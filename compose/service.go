@@ -0,0 +1,459 @@
+package compose
+
+import (
+	"bytes"
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type service struct {
+	component dit.DockerComponent
+	dependsOn []string
+	line      int
+}
+
+type converter struct {
+	projectName    string
+	workingDir     string
+	namedVolumes   map[string]struct{}
+	publishedPorts bool
+}
+
+func (r *converter) service(key *yaml.Node, value *yaml.Node) (*service, error) {
+	name := key.Value
+	context := fmt.Sprintf("service '%s'", name)
+	result := &service{component: dit.DockerComponent{Name: name}, line: key.Line}
+	component := &result.component
+
+	err := forEachKey(value, context, func(key string, value *yaml.Node) (bool, error) {
+		var err error
+		switch key {
+		case "image":
+			component.Image = value.Value
+		case "environment":
+			component.EnvironmentVariables, err = environment(value, context)
+		case "ports":
+			component.ExposedPorts, err = r.ports(value, context)
+		case "command":
+			component.Cmd, err = command(value, context+" command")
+		case "entrypoint":
+			component.Entrypoint, err = command(value, context+" entrypoint")
+		case "volumes":
+			component.Binds, err = r.volumes(value, context)
+		case "depends_on":
+			result.dependsOn, err = dependsOn(value, context)
+		case "healthcheck":
+			component.Healthcheck, err = healthcheck(value, context)
+		case "dns":
+			component.DNSServer, err = dns(value, context)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if component.Image == "" {
+		return nil, lineError(key, fmt.Sprintf("%s image must be defined", context))
+	}
+	return result, nil
+}
+
+func environment(value *yaml.Node, context string) (map[string]string, error) {
+	env := make(map[string]string)
+	switch value.Kind {
+	case yaml.SequenceNode:
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, lineError(item, fmt.Sprintf("%s environment entry must be KEY=VALUE", context))
+			}
+			parts := strings.SplitN(item.Value, "=", 2)
+			if len(parts) == 2 {
+				env[parts[0]] = parts[1]
+			} else if v, ok := os.LookupEnv(parts[0]); ok {
+				env[parts[0]] = v
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			k, v := value.Content[i], value.Content[i+1]
+			if v.Kind != yaml.ScalarNode {
+				return nil, lineError(v, fmt.Sprintf("%s environment value of '%s' must be a scalar", context, k.Value))
+			}
+			if v.Tag == "!!null" {
+				if hostValue, ok := os.LookupEnv(k.Value); ok {
+					env[k.Value] = hostValue
+				}
+				continue
+			}
+			env[k.Value] = v.Value
+		}
+	default:
+		return nil, lineError(value, fmt.Sprintf("%s environment must be a list or a mapping", context))
+	}
+	return env, nil
+}
+
+func (r *converter) ports(value *yaml.Node, context string) ([]dit.Port, error) {
+	if value.Kind != yaml.SequenceNode {
+		return nil, lineError(value, fmt.Sprintf("%s ports must be a list", context))
+	}
+	ports := make([]dit.Port, 0, len(value.Content))
+	for _, item := range value.Content {
+		var port dit.Port
+		var err error
+		if item.Kind == yaml.ScalarNode {
+			port, err = parsePortSpec(item.Value)
+			if err != nil {
+				return nil, lineError(item, fmt.Sprintf("%s %v", context, err))
+			}
+		} else {
+			port, err = portMapping(item, context)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !r.publishedPorts {
+			port.HostPort = 0
+		}
+		// the first port is the default port of the component
+		if port.Name == "" && len(ports) != 0 {
+			port.Name = strconv.Itoa(port.ContainerPort)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+// parsePortSpec parses port spec in format [[ip:]published:]target[/protocol]
+func parsePortSpec(spec string) (dit.Port, error) {
+	target := spec
+	if i := strings.LastIndex(spec, "/"); i != -1 {
+		if protocol := spec[i+1:]; protocol != "tcp" {
+			return dit.Port{}, fmt.Errorf("port '%s' protocol '%s' is not supported", spec, protocol)
+		}
+		target = spec[:i]
+	}
+	parts := strings.Split(target, ":")
+	if len(parts) > 3 {
+		return dit.Port{}, fmt.Errorf("port '%s' is invalid", spec)
+	}
+	containerPort, err := parsePortNumber(parts[len(parts)-1])
+	if err != nil {
+		return dit.Port{}, fmt.Errorf("port '%s' is invalid", spec)
+	}
+	port := dit.Port{ContainerPort: containerPort}
+	if len(parts) > 1 && parts[len(parts)-2] != "" {
+		if port.HostPort, err = parsePortNumber(parts[len(parts)-2]); err != nil {
+			return dit.Port{}, fmt.Errorf("port '%s' is invalid", spec)
+		}
+	}
+	return port, nil
+}
+
+func parsePortNumber(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	if port <= 0 || port > 65535 {
+		return 0, fmt.Errorf("port %d is out of range", port)
+	}
+	return port, nil
+}
+
+func portMapping(value *yaml.Node, context string) (dit.Port, error) {
+	var port dit.Port
+	err := forEachKey(value, context+" port", func(key string, value *yaml.Node) (bool, error) {
+		var err error
+		switch key {
+		case "target":
+			port.ContainerPort, err = parsePortNumber(value.Value)
+		case "published":
+			port.HostPort, err = parsePortNumber(value.Value)
+		case "name":
+			port.Name = value.Value
+		case "protocol":
+			if value.Value != "tcp" {
+				err = fmt.Errorf("protocol '%s' is not supported", value.Value)
+			}
+		default:
+			return false, nil
+		}
+		if err != nil {
+			return true, lineError(value, fmt.Sprintf("%s port %s %v", context, key, err))
+		}
+		return true, nil
+	})
+	if err != nil {
+		return dit.Port{}, err
+	}
+	if port.ContainerPort == 0 {
+		return dit.Port{}, lineError(value, fmt.Sprintf("%s port target must be defined", context))
+	}
+	return port, nil
+}
+
+func command(value *yaml.Node, context string) ([]string, error) {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		cmd, err := splitCommand(value.Value)
+		if err != nil {
+			return nil, lineError(value, fmt.Sprintf("%s %v", context, err))
+		}
+		return cmd, nil
+	case yaml.SequenceNode:
+		return stringList(value, context)
+	}
+	return nil, lineError(value, fmt.Sprintf("%s must be a string or a list", context))
+}
+
+// splitCommand splits a command string into words, honoring quotes and backslash escapes
+func splitCommand(command string) ([]string, error) {
+	words := make([]string, 0)
+	var word bytes.Buffer
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, c := range command {
+		switch {
+		case escaped:
+			word.WriteRune(c)
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				word.WriteRune(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("'%s' is not terminated", command)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func (r *converter) volumes(value *yaml.Node, context string) ([]string, error) {
+	if value.Kind != yaml.SequenceNode {
+		return nil, lineError(value, fmt.Sprintf("%s volumes must be a list", context))
+	}
+	binds := make([]string, 0, len(value.Content))
+	for _, item := range value.Content {
+		var source, target, mode string
+		if item.Kind == yaml.ScalarNode {
+			parts := strings.Split(item.Value, ":")
+			if len(parts) < 2 || len(parts) > 3 {
+				return nil, lineError(item, fmt.Sprintf("%s volume '%s' is not supported, expected source:target[:mode]", context, item.Value))
+			}
+			source, target = parts[0], parts[1]
+			if len(parts) == 3 {
+				mode = parts[2]
+			}
+		} else {
+			var err error
+			if source, target, mode, err = volumeMapping(item, context); err != nil {
+				return nil, err
+			}
+		}
+		bind, err := r.bind(source, target, mode)
+		if err != nil {
+			return nil, lineError(item, fmt.Sprintf("%s %v", context, err))
+		}
+		binds = append(binds, bind)
+	}
+	return binds, nil
+}
+
+func volumeMapping(value *yaml.Node, context string) (source string, target string, mode string, err error) {
+	err = forEachKey(value, context+" volume", func(key string, value *yaml.Node) (bool, error) {
+		switch key {
+		case "type":
+			if value.Value != "bind" && value.Value != "volume" {
+				return true, lineError(value, fmt.Sprintf("%s volume type '%s' is not supported", context, value.Value))
+			}
+		case "source":
+			source = value.Value
+		case "target":
+			target = value.Value
+		case "read_only":
+			if value.Value == "true" {
+				mode = "ro"
+			}
+		default:
+			return false, nil
+		}
+		return true, nil
+	})
+	if err == nil && (source == "" || target == "") {
+		err = lineError(value, fmt.Sprintf("%s volume source and target must be defined", context))
+	}
+	return
+}
+
+// bind converts a volume to a bind. Relative host paths are resolved against the working directory,
+// named volumes are prefixed with the project name.
+func (r *converter) bind(source string, target string, mode string) (string, error) {
+	switch {
+	case filepath.IsAbs(source):
+	case strings.HasPrefix(source, "~"):
+		source = filepath.Join(os.Getenv("HOME"), source[1:])
+	case strings.HasPrefix(source, "."):
+		source = filepath.Join(r.workingDir, source)
+	default:
+		if _, ok := r.namedVolumes[source]; !ok {
+			return "", fmt.Errorf("volume '%s' is not defined in the top-level volumes", source)
+		}
+		source = r.projectName + "_" + source
+	}
+	bind := source + ":" + target
+	if mode != "" {
+		bind += ":" + mode
+	}
+	return bind, nil
+}
+
+func dependsOn(value *yaml.Node, context string) ([]string, error) {
+	switch value.Kind {
+	case yaml.SequenceNode:
+		return stringList(value, context+" depends_on")
+	case yaml.MappingNode:
+		names := make([]string, 0)
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			name := value.Content[i].Value
+			if value.Content[i+1].Tag == "!!null" {
+				names = append(names, name)
+				continue
+			}
+			err := forEachKey(value.Content[i+1], context+" depends_on", func(key string, value *yaml.Node) (bool, error) {
+				if key != "condition" {
+					return false, nil
+				}
+				// services are started one after another, a healthcheck is awaited by the start
+				if value.Value != "service_started" && value.Value != "service_healthy" {
+					return true, lineError(value, fmt.Sprintf("%s depends_on condition '%s' is not supported", context, value.Value))
+				}
+				return true, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+	return nil, lineError(value, fmt.Sprintf("%s depends_on must be a list or a mapping", context))
+}
+
+func healthcheck(value *yaml.Node, context string) (*dit.Healthcheck, error) {
+	context = context + " healthcheck"
+	result := &dit.Healthcheck{}
+	disabled := false
+	err := forEachKey(value, context, func(key string, value *yaml.Node) (bool, error) {
+		var err error
+		switch key {
+		case "test":
+			result.Test, err = healthcheckTest(value, context)
+		case "interval":
+			result.Interval, err = duration(value, context)
+		case "timeout":
+			result.Timeout, err = duration(value, context)
+		case "start_period":
+			result.StartPeriod, err = duration(value, context)
+		case "retries":
+			if result.Retries, err = strconv.Atoi(value.Value); err != nil {
+				err = lineError(value, fmt.Sprintf("%s retries '%s' is invalid", context, value.Value))
+			}
+		case "disable":
+			disabled = value.Value == "true"
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return &dit.Healthcheck{Test: []string{"NONE"}}, nil
+	}
+	if len(result.Test) == 0 {
+		return nil, lineError(value, fmt.Sprintf("%s test must be defined", context))
+	}
+	return result, nil
+}
+
+func healthcheckTest(value *yaml.Node, context string) ([]string, error) {
+	if value.Kind == yaml.ScalarNode {
+		return []string{"CMD-SHELL", value.Value}, nil
+	}
+	test, err := stringList(value, context+" test")
+	if err != nil {
+		return nil, err
+	}
+	if len(test) == 0 || (test[0] != "NONE" && test[0] != "CMD" && test[0] != "CMD-SHELL") {
+		return nil, lineError(value, fmt.Sprintf("%s test must start with NONE, CMD or CMD-SHELL", context))
+	}
+	return test, nil
+}
+
+func duration(value *yaml.Node, context string) (time.Duration, error) {
+	d, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return 0, lineError(value, fmt.Sprintf("%s duration '%s' is invalid", context, value.Value))
+	}
+	return d, nil
+}
+
+func dns(value *yaml.Node, context string) (string, error) {
+	if value.Kind == yaml.ScalarNode {
+		return value.Value, nil
+	}
+	servers, err := stringList(value, context+" dns")
+	if err != nil {
+		return "", err
+	}
+	if len(servers) != 1 {
+		return "", lineError(value, fmt.Sprintf("%s dns supports exactly one server", context))
+	}
+	return servers[0], nil
+}
+
+func stringList(value *yaml.Node, context string) ([]string, error) {
+	if value.Kind != yaml.SequenceNode {
+		return nil, lineError(value, fmt.Sprintf("%s must be a list", context))
+	}
+	list := make([]string, 0, len(value.Content))
+	for _, item := range value.Content {
+		if item.Kind != yaml.ScalarNode {
+			return nil, lineError(item, fmt.Sprintf("%s entries must be scalars", context))
+		}
+		list = append(list, item.Value)
+	}
+	return list, nil
+}
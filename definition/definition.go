@@ -0,0 +1,314 @@
+// Package definition loads docker environments from declarative YAML or JSON definition files.
+package definition
+
+import (
+	"bytes"
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemaVersion is the supported version of the definition schema
+	SchemaVersion = 1
+)
+
+// Definition is the root of a definition file
+type Definition struct {
+	Version    int                   `yaml:"version"`
+	Components []ComponentDefinition `yaml:"components"`
+	// Output of the wait logs and of the followed logs of the components without logDir. If not set, stdout is used.
+	LogOutput io.Writer `yaml:"-"`
+}
+
+// ComponentDefinition defines a dit.DockerComponent
+type ComponentDefinition struct {
+	Name                    string                 `yaml:"name"`
+	Image                   string                 `yaml:"image"`
+	ForcePull               bool                   `yaml:"forcePull"`
+	PullPolicy              string                 `yaml:"pullPolicy"`
+	RegistryAuth            *RegistryAuth          `yaml:"registryAuth"`
+	Platform                string                 `yaml:"platform"`
+	ImageArchive            string                 `yaml:"imageArchive"`
+	RemoveImageAfterDestroy bool                   `yaml:"removeImageAfterDestroy"`
+	ExposedPorts            []PortDefinition       `yaml:"exposedPorts"`
+	EnvironmentVariables    map[string]string      `yaml:"environmentVariables"`
+	Cmd                     []string               `yaml:"cmd"`
+	Entrypoint              []string               `yaml:"entrypoint"`
+	Binds                   []string               `yaml:"binds"`
+	DNSServer               string                 `yaml:"dnsServer"`
+	Healthcheck             *HealthcheckDefinition `yaml:"healthcheck"`
+	FollowLogs              bool                   `yaml:"followLogs"`
+	// Directory of the component log file, see dit.NewFileLogConsumer
+	LogDir        string          `yaml:"logDir"`
+	LogTimestamps bool            `yaml:"logTimestamps"`
+	LogBufferSize int             `yaml:"logBufferSize"`
+	ErrorLogLines int             `yaml:"errorLogLines"`
+	AfterStart    *WaitDefinition `yaml:"afterStart"`
+
+	line int
+}
+
+// RegistryAuth defines dit.RegistryAuth
+type RegistryAuth struct {
+	Username      string `yaml:"username"`
+	Password      string `yaml:"password"`
+	IdentityToken string `yaml:"identityToken"`
+}
+
+// HealthcheckDefinition defines a dit.Healthcheck, the durations are given as e.g. 5s
+type HealthcheckDefinition struct {
+	Test        []string      `yaml:"test"`
+	Interval    time.Duration `yaml:"interval"`
+	Timeout     time.Duration `yaml:"timeout"`
+	Retries     int           `yaml:"retries"`
+	StartPeriod time.Duration `yaml:"startPeriod"`
+}
+
+// PortDefinition defines a dit.Port. It is given either as a port spec "[hostPort:]containerPort"
+// or as a mapping with name, containerPort and hostPort keys.
+type PortDefinition struct {
+	Name          string
+	ContainerPort int
+	HostPort      int
+}
+
+type portMapping struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"containerPort"`
+	HostPort      int    `yaml:"hostPort"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (r *PortDefinition) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		port, err := parsePortSpec(value.Value)
+		if err != nil {
+			return lineError(value, err.Error())
+		}
+		*r = port
+		return nil
+	}
+	var mapping portMapping
+	if err := decodeStrict(value, &mapping); err != nil {
+		return err
+	}
+	if err := validatePort(mapping.ContainerPort, mapping.HostPort); err != nil {
+		return lineError(value, err.Error())
+	}
+	*r = PortDefinition(mapping)
+	return nil
+}
+
+// parsePortSpec parses port spec in format [hostPort:]containerPort
+func parsePortSpec(spec string) (PortDefinition, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 2 {
+		return PortDefinition{}, fmt.Errorf("port spec '%s' is invalid, expected [hostPort:]containerPort", spec)
+	}
+	ports := make([]int, len(parts))
+	for i, part := range parts {
+		port, err := strconv.Atoi(part)
+		if err != nil {
+			return PortDefinition{}, fmt.Errorf("port spec '%s' is invalid, expected [hostPort:]containerPort", spec)
+		}
+		ports[i] = port
+	}
+	if len(ports) == 1 {
+		return PortDefinition{ContainerPort: ports[0]}, validatePort(ports[0], 0)
+	}
+	return PortDefinition{ContainerPort: ports[1], HostPort: ports[0]}, validatePort(ports[1], ports[0])
+}
+
+func validatePort(containerPort int, hostPort int) error {
+	if containerPort <= 0 || containerPort > 65535 {
+		return fmt.Errorf("containerPort %d is invalid", containerPort)
+	}
+	if hostPort < 0 || hostPort > 65535 {
+		return fmt.Errorf("hostPort %d is invalid", hostPort)
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (r *ComponentDefinition) UnmarshalYAML(value *yaml.Node) error {
+	type plain ComponentDefinition
+	if err := decodeStrict(value, (*plain)(r)); err != nil {
+		return err
+	}
+	r.line = value.Line
+	return nil
+}
+
+// Load reads the definition from YAML or JSON
+func Load(reader io.Reader) (*Definition, error) {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, fmt.Errorf("definition is empty")
+	}
+	document := root.Content[0]
+	definition := &Definition{}
+	if err := decodeStrict(document, definition); err != nil {
+		return nil, err
+	}
+	if definition.Version != SchemaVersion {
+		return nil, lineError(findKey(document, "version"), fmt.Sprintf("version %d is not supported, expected %d", definition.Version, SchemaVersion))
+	}
+	if len(definition.Components) == 0 {
+		return nil, lineError(document, "components must not be empty")
+	}
+	return definition, nil
+}
+
+// LoadFile reads the definition from a YAML or JSON file
+func LoadFile(path string) (*Definition, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	definition, err := Load(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return definition, nil
+}
+
+// DockerComponents converts the definition to docker components
+func (r *Definition) DockerComponents() ([]dit.DockerComponent, error) {
+	components := make([]dit.DockerComponent, 0, len(r.Components))
+	for _, definition := range r.Components {
+		component, err := definition.dockerComponent(r.LogOutput)
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, component)
+	}
+	return components, nil
+}
+
+// DockerComponent converts the definition to a docker component
+func (r *ComponentDefinition) DockerComponent() (dit.DockerComponent, error) {
+	return r.dockerComponent(nil)
+}
+
+func (r *ComponentDefinition) dockerComponent(logOutput io.Writer) (dit.DockerComponent, error) {
+	if r.Name == "" || r.Image == "" {
+		return dit.DockerComponent{}, fmt.Errorf("line %d: component name and image must not be empty", r.line)
+	}
+	component := dit.DockerComponent{
+		Name:                    r.Name,
+		Image:                   r.Image,
+		ForcePull:               r.ForcePull,
+		PullPolicy:              dit.PullPolicy(r.PullPolicy),
+		Platform:                r.Platform,
+		ImageArchive:            r.ImageArchive,
+		RemoveImageAfterDestroy: r.RemoveImageAfterDestroy,
+		EnvironmentVariables:    r.EnvironmentVariables,
+		Cmd:                     r.Cmd,
+		Entrypoint:              r.Entrypoint,
+		Binds:                   r.Binds,
+		DNSServer:               r.DNSServer,
+		FollowLogs:              r.FollowLogs,
+		LogTimestamps:           r.LogTimestamps,
+		LogBufferSize:           r.LogBufferSize,
+		ErrorLogLines:           r.ErrorLogLines,
+	}
+	if r.RegistryAuth != nil {
+		component.RegistryAuth = &dit.RegistryAuth{
+			Username:      r.RegistryAuth.Username,
+			Password:      r.RegistryAuth.Password,
+			IdentityToken: r.RegistryAuth.IdentityToken,
+		}
+	}
+	if r.Healthcheck != nil {
+		if r.Healthcheck.Interval < 0 || r.Healthcheck.Timeout < 0 || r.Healthcheck.StartPeriod < 0 || r.Healthcheck.Retries < 0 {
+			return dit.DockerComponent{}, fmt.Errorf("line %d: healthcheck durations and retries must not be negative", r.line)
+		}
+		component.Healthcheck = &dit.Healthcheck{
+			Test:        r.Healthcheck.Test,
+			Interval:    r.Healthcheck.Interval,
+			Timeout:     r.Healthcheck.Timeout,
+			Retries:     r.Healthcheck.Retries,
+			StartPeriod: r.Healthcheck.StartPeriod,
+		}
+	}
+	for _, port := range r.ExposedPorts {
+		component.ExposedPorts = append(component.ExposedPorts, dit.Port{
+			Name:          port.Name,
+			ContainerPort: port.ContainerPort,
+			HostPort:      port.HostPort,
+		})
+	}
+	if r.LogDir != "" {
+		component.LogConsumer = dit.NewFileLogConsumer(r.LogDir)
+	} else if logOutput != nil {
+		component.LogConsumer = dit.NewWriterLogConsumer(logOutput, nil)
+	}
+	if r.AfterStart != nil {
+		var waitLogger *log.Logger
+		if logOutput != nil {
+			waitLogger = log.New(logOutput, fmt.Sprintf("WAIT FOR %s: ", r.Name), log.Ldate|log.Ltime)
+		}
+		callback, err := r.AfterStart.callback(waitLogger)
+		if err != nil {
+			return dit.DockerComponent{}, err
+		}
+		component.AfterStart = callback
+	}
+	return component, nil
+}
+
+// NewDockerEnvironmentFromFile creates a new docker test environment from a YAML or JSON definition file
+func NewDockerEnvironmentFromFile(path string) (*dit.DockerEnvironment, error) {
+	definition, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	components, err := definition.DockerComponents()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return dit.NewDockerEnvironment(components...)
+}
+
+// decodeStrict decodes the node and fails on unknown keys
+func decodeStrict(value *yaml.Node, out interface{}) error {
+	if value.Kind == yaml.MappingNode {
+		if err := checkKnownKeys(value, out); err != nil {
+			return err
+		}
+	}
+	return value.Decode(out)
+}
+
+func lineError(value *yaml.Node, message string) error {
+	if value == nil {
+		return fmt.Errorf("%s", message)
+	}
+	return fmt.Errorf("line %d: %s", value.Line, message)
+}
+
+func findKey(mapping *yaml.Node, key string) *yaml.Node {
+	if mapping.Kind != yaml.MappingNode {
+		return mapping
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,163 @@
+package definition
+
+import (
+	"bytes"
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFileYAML(t *testing.T) {
+	a := assert.New(t)
+
+	definition, err := LoadFile("testdata/environment.yaml")
+	a.Nil(err)
+	a.Len(definition.Components, 2)
+
+	redis := definition.Components[0]
+	a.Equal("it-redis", redis.Name)
+	a.Equal("IfNotPresent", redis.PullPolicy)
+	a.True(redis.FollowLogs)
+	a.Equal([]PortDefinition{{ContainerPort: 6379}}, redis.ExposedPorts)
+	a.NotNil(redis.AfterStart.Redis)
+
+	postgres := definition.Components[1]
+	a.Equal([]PortDefinition{{Name: "postgres", ContainerPort: 5432, HostPort: 15432}}, postgres.ExposedPorts)
+	a.Equal(map[string]string{"POSTGRES_PASSWORD": "postgres"}, postgres.EnvironmentVariables)
+	a.Equal(60*time.Second, postgres.AfterStart.Postgres.AtMost)
+	a.Equal(&HealthcheckDefinition{Test: []string{"CMD", "pg_isready", "-U", "postgres"}, Interval: 2 * time.Second, Timeout: time.Second, Retries: 5, StartPeriod: 10 * time.Second}, postgres.Healthcheck)
+
+	components, err := definition.DockerComponents()
+	a.Nil(err)
+	a.Len(components, 2)
+	a.Equal(dit.PullIfNotPresent, components[0].PullPolicy)
+	a.Equal([]dit.Port{{ContainerPort: 6379}}, components[0].ExposedPorts)
+	a.NotNil(components[0].AfterStart)
+	a.NotNil(components[1].AfterStart)
+	a.Nil(components[0].Healthcheck)
+	a.Equal(&dit.Healthcheck{Test: []string{"CMD", "pg_isready", "-U", "postgres"}, Interval: 2 * time.Second, Timeout: time.Second, Retries: 5, StartPeriod: 10 * time.Second}, components[1].Healthcheck)
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	a := assert.New(t)
+
+	definition, err := LoadFile("testdata/environment.json")
+	a.Nil(err)
+	a.Len(definition.Components, 1)
+
+	component := definition.Components[0]
+	a.Equal("it-http", component.Name)
+	a.Equal([]PortDefinition{{ContainerPort: 8080, HostPort: 18080}}, component.ExposedPorts)
+	a.Equal([]string{"--verbose"}, component.Cmd)
+	a.Equal(500*time.Millisecond, component.AfterStart.HTTP.PollInterval)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		err        string
+	}{
+		{
+			name:       "unknown key",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    imagee: redis\n",
+			err:        "line 4: unknown key 'imagee'",
+		},
+		{
+			name:       "unknown nested key",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    registryAuth:\n      user: me\n",
+			err:        "line 6: unknown key 'user'",
+		},
+		{
+			name:       "unknown healthcheck key",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    healthcheck:\n      start_period: 5s\n",
+			err:        "line 6: unknown key 'start_period', expected one of interval, retries, startPeriod, test, timeout",
+		},
+		{
+			name:       "invalid healthcheck interval",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    healthcheck:\n      interval: often\n",
+			err:        "line 6:",
+		},
+		{
+			name:       "invalid port",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    exposedPorts:\n      - 70000\n",
+			err:        "line 6: containerPort 70000 is invalid",
+		},
+		{
+			name:       "invalid port spec",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    exposedPorts:\n      - a:b:c\n",
+			err:        "line 6: port spec 'a:b:c' is invalid",
+		},
+		{
+			name:       "unsupported version",
+			definition: "version: 2\ncomponents:\n  - name: it-redis\n    image: redis\n",
+			err:        "line 1: version 2 is not supported",
+		},
+		{
+			name:       "no components",
+			definition: "version: 1\n",
+			err:        "line 1: components must not be empty",
+		},
+		{
+			name:       "multiple waits",
+			definition: "version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    afterStart:\n      redis: {}\n      http: {url: 'http://localhost'}\n",
+			err:        "line 6: afterStart must define exactly one wait",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := assert.New(t)
+			_, err := Load(strings.NewReader(test.definition))
+			if a.NotNil(err) {
+				a.Contains(err.Error(), test.err)
+			}
+		})
+	}
+}
+
+func TestDockerComponentErrors(t *testing.T) {
+	a := assert.New(t)
+
+	definition, err := Load(strings.NewReader("version: 1\ncomponents:\n  - name: it-http\n    image: wiremock\n    afterStart:\n      http: {method: GET}\n"))
+	a.Nil(err)
+	_, err = definition.DockerComponents()
+	a.EqualError(err, "line 6: http wait url must not be empty")
+
+	definition, err = Load(strings.NewReader("version: 1\ncomponents:\n  - name: it-redis\n    image: redis\n    healthcheck:\n      retries: -1\n"))
+	a.Nil(err)
+	_, err = definition.DockerComponents()
+	a.EqualError(err, "line 3: healthcheck durations and retries must not be negative")
+
+	definition, err = Load(strings.NewReader("version: 1\ncomponents:\n  - image: redis\n"))
+	a.Nil(err)
+	_, err = definition.DockerComponents()
+	a.EqualError(err, "line 3: component name and image must not be empty")
+}
+
+func TestDockerComponentsLogOutput(t *testing.T) {
+	a := assert.New(t)
+
+	definition, err := LoadFile("testdata/environment.yaml")
+	a.Nil(err)
+	var output bytes.Buffer
+	definition.LogOutput = &output
+	components, err := definition.DockerComponents()
+	a.Nil(err)
+
+	stdout, stderr, err := components[0].LogConsumer.Writers(components[0].Name)
+	a.Nil(err)
+	fmt.Fprintln(stdout, "ready to accept connections")
+	fmt.Fprintln(stderr, "warning")
+	a.Equal("ready to accept connections\nwarning\n", output.String())
+
+	output.Reset()
+	logger := components[0].AfterStart.(interface {
+		GetLogger(string) *log.Logger
+	}).GetLogger(components[0].Name)
+	logger.Println("waiting")
+	a.Contains(output.String(), "WAIT FOR it-redis: ")
+}
@@ -0,0 +1,86 @@
+package definition
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// checkKnownKeys fails on mapping keys not defined by yaml tags of the struct. Nested structs are checked
+// unless they implement yaml.Unmarshaler.
+func checkKnownKeys(value *yaml.Node, out interface{}) error {
+	return checkNodeKeys(value, reflect.TypeOf(out))
+}
+
+func checkNodeKeys(value *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch value.Kind {
+	case yaml.SequenceNode:
+		if t.Kind() != reflect.Slice {
+			return nil
+		}
+		for _, item := range value.Content {
+			if err := checkNestedKeys(item, t.Elem()); err != nil {
+				return err
+			}
+		}
+	case yaml.MappingNode:
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			key := value.Content[i]
+			field, ok := fields[key.Value]
+			if !ok {
+				return lineError(key, fmt.Sprintf("unknown key '%s', expected one of %s", key.Value, strings.Join(sortedKeys(fields), ", ")))
+			}
+			if err := checkNestedKeys(value.Content[i+1], field.Type); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkNestedKeys(value *yaml.Node, t reflect.Type) error {
+	if t.Implements(unmarshalerType) || reflect.PtrTo(t).Implements(unmarshalerType) {
+		// checked by the type itself
+		return nil
+	}
+	return checkNodeKeys(value, t)
+}
+
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		if len(tag) > 1 && tag[1] == "inline" {
+			for name, inlineField := range yamlFields(field.Type) {
+				fields[name] = inlineField
+			}
+			continue
+		}
+		if tag[0] == "" || tag[0] == "-" {
+			continue
+		}
+		fields[tag[0]] = field
+	}
+	return fields
+}
+
+func sortedKeys(fields map[string]reflect.StructField) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package definition
+
+import (
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"github.com/grepplabs/docker-it/wait"
+	"github.com/grepplabs/docker-it/wait/database"
+	"github.com/grepplabs/docker-it/wait/elastic"
+	"github.com/grepplabs/docker-it/wait/http"
+	"github.com/grepplabs/docker-it/wait/kafka"
+	"github.com/grepplabs/docker-it/wait/mysql"
+	"github.com/grepplabs/docker-it/wait/postgres"
+	"github.com/grepplabs/docker-it/wait/redis"
+	"gopkg.in/yaml.v3"
+	"log"
+	"time"
+)
+
+// WaitDefinition defines the AfterStart callback. Exactly one wait must be given.
+type WaitDefinition struct {
+	HTTP     *HTTPWait     `yaml:"http"`
+	Redis    *RedisWait    `yaml:"redis"`
+	Kafka    *KafkaWait    `yaml:"kafka"`
+	Postgres *DatabaseWait `yaml:"postgres"`
+	MySQL    *DatabaseWait `yaml:"mysql"`
+	Elastic  *ElasticWait  `yaml:"elastic"`
+	Database *DatabaseWait `yaml:"database"`
+
+	line int
+}
+
+// WaitOptions defines wait.Options
+type WaitOptions struct {
+	AtMost       time.Duration `yaml:"atMost"`
+	PollInterval time.Duration `yaml:"pollInterval"`
+}
+
+// HTTPWait defines http.NewHttpWait
+type HTTPWait struct {
+	WaitOptions `yaml:",inline"`
+	URL         string `yaml:"url"`
+	Method      string `yaml:"method"`
+}
+
+// RedisWait defines redis.NewRedisWait
+type RedisWait struct {
+	WaitOptions `yaml:",inline"`
+	PortName    string `yaml:"portName"`
+}
+
+// KafkaWait defines kafka.NewKafkaWait
+type KafkaWait struct {
+	WaitOptions `yaml:",inline"`
+	BrokerAddr  string `yaml:"brokerAddr"`
+	Topic       string `yaml:"topic"`
+}
+
+// DatabaseWait defines postgres.NewPostgresWait, mysql.NewMySQLWait and database.NewDatabaseWait.
+// The driver is used only by the database wait.
+type DatabaseWait struct {
+	WaitOptions `yaml:",inline"`
+	URL         string `yaml:"url"`
+	Driver      string `yaml:"driver"`
+}
+
+// ElasticWait defines elastic.NewElasticWait
+type ElasticWait struct {
+	WaitOptions `yaml:",inline"`
+	URL         string `yaml:"url"`
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (r *WaitDefinition) UnmarshalYAML(value *yaml.Node) error {
+	type plain WaitDefinition
+	if err := decodeStrict(value, (*plain)(r)); err != nil {
+		return err
+	}
+	r.line = value.Line
+
+	count := 0
+	for _, defined := range []bool{r.HTTP != nil, r.Redis != nil, r.Kafka != nil, r.Postgres != nil, r.MySQL != nil, r.Elastic != nil, r.Database != nil} {
+		if defined {
+			count++
+		}
+	}
+	if count != 1 {
+		return lineError(value, "afterStart must define exactly one wait of http, redis, kafka, postgres, mysql, elastic or database")
+	}
+	return nil
+}
+
+func (r WaitOptions) waitOptions(logger *log.Logger) wait.Options {
+	return wait.Options{AtMost: r.AtMost, PollInterval: r.PollInterval, Logger: logger}
+}
+
+// Callback creates the wait
+func (r *WaitDefinition) Callback() (dit.Callback, error) {
+	return r.callback(nil)
+}
+
+// callback creates the wait logging to the logger, the wait logs to stdout if the logger is nil
+func (r *WaitDefinition) callback(logger *log.Logger) (dit.Callback, error) {
+	switch {
+	case r.HTTP != nil:
+		if r.HTTP.URL == "" {
+			return nil, r.requiredError("http", "url")
+		}
+		return http.NewHttpWait(r.HTTP.URL, http.Options{WaitOptions: r.HTTP.waitOptions(logger), Method: r.HTTP.Method}), nil
+	case r.Redis != nil:
+		return redis.NewRedisWait(redis.Options{WaitOptions: r.Redis.waitOptions(logger), PortName: r.Redis.PortName}), nil
+	case r.Kafka != nil:
+		if r.Kafka.BrokerAddr == "" {
+			return nil, r.requiredError("kafka", "brokerAddr")
+		}
+		return kafka.NewKafkaWait(r.Kafka.BrokerAddr, kafka.Options{WaitOptions: r.Kafka.waitOptions(logger), Topic: r.Kafka.Topic}), nil
+	case r.Postgres != nil:
+		if r.Postgres.URL == "" {
+			return nil, r.requiredError("postgres", "url")
+		}
+		return postgres.NewPostgresWait(r.Postgres.URL, postgres.Options{WaitOptions: r.Postgres.waitOptions(logger)}), nil
+	case r.MySQL != nil:
+		if r.MySQL.URL == "" {
+			return nil, r.requiredError("mysql", "url")
+		}
+		return mysql.NewMySQLWait(r.MySQL.URL, mysql.Options{WaitOptions: r.MySQL.waitOptions(logger)}), nil
+	case r.Elastic != nil:
+		if r.Elastic.URL == "" {
+			return nil, r.requiredError("elastic", "url")
+		}
+		return elastic.NewElasticWait(r.Elastic.URL, elastic.Options{
+			WaitOptions: r.Elastic.waitOptions(logger),
+			Username:    r.Elastic.Username,
+			Password:    r.Elastic.Password,
+		}), nil
+	case r.Database != nil:
+		if r.Database.Driver == "" {
+			return nil, r.requiredError("database", "driver")
+		}
+		if r.Database.URL == "" {
+			return nil, r.requiredError("database", "url")
+		}
+		return database.NewDatabaseWait(r.Database.Driver, r.Database.URL, database.Options{WaitOptions: r.Database.waitOptions(logger)}), nil
+	}
+	return nil, fmt.Errorf("line %d: afterStart wait is not defined", r.line)
+}
+
+func (r *WaitDefinition) requiredError(waitName string, key string) error {
+	return fmt.Errorf("line %d: %s wait %s must not be empty", r.line, waitName, key)
+}
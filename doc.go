@@ -0,0 +1,5 @@
+// Package dockerit runs docker containers as components of integration tests.
+//
+// The package imports the testing package for New, which creates an environment for a test. Programs importing
+// dockerit link the testing package, its command line flags are registered only by testing.Init, which go test calls.
+package dockerit
@@ -2,31 +2,47 @@ package dockerit
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/docker/docker/api/types"
 	typesContainer "github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/events"
 	typesFilters "github.com/docker/docker/api/types/filters"
+	typesNetwork "github.com/docker/docker/api/types/network"
 	typesStrslice "github.com/docker/docker/api/types/strslice"
+	typesVolume "github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/docker/pkg/stringid"
 	"github.com/docker/go-connections/nat"
 	"io"
 	"io/ioutil"
+	"log"
+	"os"
+	"time"
 )
 
 type dockerClient struct {
 	client *client.Client
 }
 
-// newDockerClient initializes a new API docker.NewEnvClient client based on environment variables.
+// newDockerClient initializes a new API docker client, see ClientConfig. The zero config uses environment variables.
 // Use DOCKER_HOST to set the url to the docker server.
-// Use DOCKER_API_VERSION to set the version of the API to reach, leave empty for latest.
+// Use DOCKER_API_VERSION to set the version of the API to reach, leave empty to negotiate the version with the daemon.
 // Use DOCKER_CERT_PATH to load the TLS certificates from.
 // Use DOCKER_TLS_VERIFY to enable or disable TLS verification, off by default.
-func newDockerClient() (*dockerClient, error) {
-	cli, err := client.NewEnvClient()
+// The daemon is pinged, a *DaemonError is returned if it is unreachable or not supported.
+func newDockerClient(config ClientConfig) (*dockerClient, error) {
+	cli, host, err := newAPIClient(config)
 	if err != nil {
 		return nil, err
 	}
+	pinned := config.APIVersion != "" || os.Getenv("DOCKER_API_VERSION") != ""
+	if err := negotiateAPIVersion(cli, host, pinned); err != nil {
+		cli.Close()
+		return nil, err
+	}
 	return &dockerClient{client: cli}, nil
 }
 
@@ -50,6 +66,26 @@ func (r *dockerClient) GetContainerByID(containerID string) (*types.Container, e
 	return nil, nil
 }
 
+// GetContainerByName returns the container with the given name from the docker host.
+func (r *dockerClient) GetContainerByName(containerName string) (*types.Container, error) {
+	containerFilters := typesFilters.NewArgs()
+	containerFilters.Add("name", containerName)
+	options := types.ContainerListOptions{All: true, Filters: containerFilters}
+	containers, err := r.client.ContainerList(context.Background(), options)
+	if err != nil {
+		return nil, err
+	}
+	// name filter matches substrings
+	for _, container := range containers {
+		for _, name := range container.Names {
+			if name == "/"+containerName {
+				return &container, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
 // GetImageByName returns first image for a given name from a list of images in the docker host.
 func (r *dockerClient) GetImageByName(imageName string) (*types.ImageSummary, error) {
 	// https://docs.docker.com/engine/api/v1.29/#operation/ImageList
@@ -94,44 +130,124 @@ func (r *dockerClient) RemoveImage(imageID string) error {
 }
 
 // PullImage requests the docker host to pull an image from a remote registry.
-func (r *dockerClient) PullImage(imageName string) error {
-	options := types.ImagePullOptions{}
+// The platform selects the image of a multi-arch image, the registryAuth is base64 encoded auth config, empty for anonymous pull.
+// The pull progress is periodically logged, if the logger is provided.
+func (r *dockerClient) PullImage(imageName string, platform string, registryAuth string, logger *log.Logger) error {
+	options := types.ImagePullOptions{RegistryAuth: registryAuth, Platform: platform}
 	resp, err := r.client.ImagePull(context.Background(), imageName, options)
 	if err != nil {
 		return err
 	}
-	_, err = ioutil.ReadAll(resp)
+	defer resp.Close()
+	return newPullProgress(imageName, logger).decode(resp)
+}
+
+// LoadImage loads images from a tar archive created by docker save.
+func (r *dockerClient) LoadImage(archive io.Reader) error {
+	resp, err := r.client.ImageLoad(context.Background(), archive, true)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer resp.Body.Close()
+	if !resp.JSON {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if message.Error != nil {
+			return fmt.Errorf("Load image failed: %s", message.Error.Message)
+		}
+		if message.ErrorMessage != "" {
+			return fmt.Errorf("Load image failed: %s", message.ErrorMessage)
+		}
+	}
 }
 
-// CreateContainer creates a new container.
-func (r *dockerClient) CreateContainer(containerName string, image string, env []string, portSpecs []string, cmd []string, binds []string, dnsServer string) (string, error) {
+// SaveImages writes a tar archive of the images created as by docker save.
+func (r *dockerClient) SaveImages(imageNames []string, w io.Writer) error {
+	reader, err := r.client.ImageSave(context.Background(), imageNames)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+// TagImage creates the target tag referring to the source image.
+func (r *dockerClient) TagImage(source string, target string) error {
+	return r.client.ImageTag(context.Background(), source, target)
+}
+
+// InspectImage returns the low-level information of an image.
+func (r *dockerClient) InspectImage(imageName string) (*types.ImageInspect, error) {
+	image, _, err := r.client.ImageInspectWithRaw(context.Background(), imageName)
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+// CreateContainer creates a new container. If the network is given, the container is connected to it with the network alias.
+func (r *dockerClient) CreateContainer(containerConfig ContainerConfig) (string, error) {
 	// ip:public:private/proto
-	exposedPorts, portBindings, err := nat.ParsePortSpecs(portSpecs)
+	exposedPorts, portBindings, err := nat.ParsePortSpecs(containerConfig.PortSpecs)
+	if err != nil {
+		return "", err
+	}
+	platform, err := parsePlatform(containerConfig.Platform)
 	if err != nil {
 		return "", err
 	}
 	config := typesContainer.Config{
-		Image:        image,
-		Env:          env,
+		Image:        containerConfig.Image,
+		Env:          containerConfig.Env,
 		ExposedPorts: exposedPorts,
-		Cmd:          typesStrslice.StrSlice(cmd),
+		Cmd:          typesStrslice.StrSlice(containerConfig.Cmd),
+		Labels:       containerConfig.Labels,
+	}
+	if len(containerConfig.Entrypoint) != 0 {
+		config.Entrypoint = typesStrslice.StrSlice(containerConfig.Entrypoint)
+	}
+	if healthcheck := containerConfig.Healthcheck; healthcheck != nil {
+		config.Healthcheck = &typesContainer.HealthConfig{
+			Test:        healthcheck.Test,
+			Interval:    healthcheck.Interval,
+			Timeout:     healthcheck.Timeout,
+			Retries:     healthcheck.Retries,
+			StartPeriod: healthcheck.StartPeriod,
+		}
 	}
 	dns := make([]string, 0)
-	if dnsServer != "" {
-		dns = append(dns, dnsServer)
+	if containerConfig.DNSServer != "" {
+		dns = append(dns, containerConfig.DNSServer)
 	}
 
 	hostConfig := typesContainer.HostConfig{
 		PortBindings: portBindings,
-		Binds:        binds,
+		Binds:        containerConfig.Binds,
 		DNS:          dns,
 	}
 
-	body, err := r.client.ContainerCreate(context.Background(), &config, &hostConfig, nil, containerName)
+	var networkingConfig *typesNetwork.NetworkingConfig
+	if containerConfig.Network != "" {
+		hostConfig.NetworkMode = typesContainer.NetworkMode(containerConfig.Network)
+		networkingConfig = &typesNetwork.NetworkingConfig{
+			EndpointsConfig: map[string]*typesNetwork.EndpointSettings{
+				containerConfig.Network: {Aliases: []string{containerConfig.NetworkAlias}},
+			},
+		}
+	}
+
+	body, err := r.client.ContainerCreate(context.Background(), &config, &hostConfig, networkingConfig, platform, containerConfig.Name)
 	if err != nil {
 		return "", err
 	}
@@ -146,14 +262,50 @@ func (r *dockerClient) StartContainer(containerID string) error {
 }
 
 // ContainerLogs returns the logs generated by a container in an io.ReadCloser.
-func (r *dockerClient) ContainerLogs(containerID string, follow bool) (io.ReadCloser, error) {
-	options := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: follow}
+// Logs created before since are skipped, unless since is zero.
+func (r *dockerClient) ContainerLogs(containerID string, follow bool, timestamps bool, since time.Time) (io.ReadCloser, error) {
+	options := types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true, Follow: follow, Timestamps: timestamps}
+	if !since.IsZero() {
+		options.Since = fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond())
+	}
 	return r.client.ContainerLogs(context.Background(), containerID, options)
 }
 
 // StopContainer stops a container without terminating the process.
-func (r *dockerClient) StopContainer(containerID string) error {
-	return r.client.ContainerStop(context.Background(), containerID, nil)
+// The container is killed after the timeout, if nil the docker default is used.
+func (r *dockerClient) StopContainer(containerID string, timeout *time.Duration) error {
+	return r.client.ContainerStop(context.Background(), containerID, timeout)
+}
+
+// InspectContainer returns the low-level information of a container.
+func (r *dockerClient) InspectContainer(containerID string) (*types.ContainerJSON, error) {
+	container, err := r.client.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return nil, err
+	}
+	return &container, nil
+}
+
+// ExecContainer runs a command in a running container and returns its exit code.
+func (r *dockerClient) ExecContainer(containerID string, cmd []string, stdout io.Writer, stderr io.Writer) (int, error) {
+	config := types.ExecConfig{AttachStdout: true, AttachStderr: true, Cmd: cmd}
+	exec, err := r.client.ContainerExecCreate(context.Background(), containerID, config)
+	if err != nil {
+		return 0, err
+	}
+	response, err := r.client.ContainerExecAttach(context.Background(), exec.ID, types.ExecStartCheck{})
+	if err != nil {
+		return 0, err
+	}
+	defer response.Close()
+	if _, err := stdcopy.StdCopy(stdout, stderr, response.Reader); err != nil {
+		return 0, err
+	}
+	inspect, err := r.client.ContainerExecInspect(context.Background(), exec.ID)
+	if err != nil {
+		return 0, err
+	}
+	return inspect.ExitCode, nil
 }
 
 // RemoveContainer kills and removes a container from the docker host.
@@ -162,6 +314,97 @@ func (r *dockerClient) RemoveContainer(containerID string) error {
 	return r.client.ContainerRemove(context.Background(), containerID, options)
 }
 
+// ListContainers returns all containers of the docker host.
+func (r *dockerClient) ListContainers() ([]types.Container, error) {
+	return r.client.ContainerList(context.Background(), types.ContainerListOptions{All: true})
+}
+
+// ListNetworks returns the networks having the label.
+func (r *dockerClient) ListNetworks(label string) ([]types.NetworkResource, error) {
+	networkFilters := typesFilters.NewArgs()
+	networkFilters.Add("label", label)
+	return r.client.NetworkList(context.Background(), types.NetworkListOptions{Filters: networkFilters})
+}
+
+// GetNetworkByName returns the network with the given name from the docker host.
+func (r *dockerClient) GetNetworkByName(networkName string) (*types.NetworkResource, error) {
+	networkFilters := typesFilters.NewArgs()
+	networkFilters.Add("name", networkName)
+	networks, err := r.client.NetworkList(context.Background(), types.NetworkListOptions{Filters: networkFilters})
+	if err != nil {
+		return nil, err
+	}
+	// name filter matches substrings
+	for _, network := range networks {
+		if network.Name == networkName {
+			return &network, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateNetwork creates a bridge network.
+func (r *dockerClient) CreateNetwork(networkName string, labels map[string]string) (string, error) {
+	options := types.NetworkCreate{CheckDuplicate: true, Driver: "bridge", Labels: labels}
+	response, err := r.client.NetworkCreate(context.Background(), networkName, options)
+	if err != nil {
+		return "", err
+	}
+	return response.ID, nil
+}
+
+// RemoveNetwork removes a network from the docker host.
+func (r *dockerClient) RemoveNetwork(networkID string) error {
+	return r.client.NetworkRemove(context.Background(), networkID)
+}
+
+// GetVolumeByName returns the volume with the given name from the docker host.
+func (r *dockerClient) GetVolumeByName(volumeName string) (*types.Volume, error) {
+	volumeFilters := typesFilters.NewArgs()
+	volumeFilters.Add("name", volumeName)
+	body, err := r.client.VolumeList(context.Background(), volumeFilters)
+	if err != nil {
+		return nil, err
+	}
+	// name filter matches substrings
+	for _, volume := range body.Volumes {
+		if volume.Name == volumeName {
+			return volume, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateVolume creates a local volume.
+func (r *dockerClient) CreateVolume(volumeName string, labels map[string]string) error {
+	_, err := r.client.VolumeCreate(context.Background(), typesVolume.VolumeCreateBody{Name: volumeName, Labels: labels})
+	return err
+}
+
+// ListVolumes returns the volumes having the label.
+func (r *dockerClient) ListVolumes(label string) ([]*types.Volume, error) {
+	volumeFilters := typesFilters.NewArgs()
+	volumeFilters.Add("label", label)
+	body, err := r.client.VolumeList(context.Background(), volumeFilters)
+	if err != nil {
+		return nil, err
+	}
+	return body.Volumes, nil
+}
+
+// RemoveVolume removes a volume from the docker host.
+func (r *dockerClient) RemoveVolume(volumeName string) error {
+	return r.client.VolumeRemove(context.Background(), volumeName, false)
+}
+
+// Events returns the container events of the containers having the label until the context is done.
+func (r *dockerClient) Events(ctx context.Context, label string) (<-chan events.Message, <-chan error) {
+	eventFilters := typesFilters.NewArgs()
+	eventFilters.Add("type", events.ContainerEventType)
+	eventFilters.Add("label", label)
+	return r.client.Events(ctx, types.EventsOptions{Filters: eventFilters})
+}
+
 // TruncateID returns a shorthand version of a string identifier.
 func TruncateID(id string) string {
 	return stringid.TruncateID(id)
@@ -0,0 +1,167 @@
+package dockerit
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-connections/tlsconfig"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	defaultDockerContext = "default"
+)
+
+// defaultDockerSocket is the socket of the docker daemon running as root
+var defaultDockerSocket = "/var/run/docker.sock"
+
+// ClientConfig configures the docker client. If Host is empty, the docker host is taken from DOCKER_HOST, the current
+// docker CLI context, or the socket of the rootless docker daemon in $XDG_RUNTIME_DIR, if the root daemon socket does not exist.
+// The client is configured with the environment variables DOCKER_HOST, DOCKER_API_VERSION, DOCKER_CERT_PATH
+// and DOCKER_TLS_VERIFY, the fields which are set override them.
+type ClientConfig struct {
+	// Docker host, e.g. unix:///var/run/docker.sock or tcp://10.0.0.1:2376
+	Host string
+	// Docker API version. If not specified, DOCKER_API_VERSION is used or the version is negotiated with the daemon.
+	APIVersion string
+	// TLS configuration of the connection to the docker host
+	TLSConfig *tls.Config
+	// HTTP client sending the docker API requests, its transport must be *http.Transport. TLSConfig is ignored if set.
+	HTTPClient *http.Client
+}
+
+// dockerContextMeta is the metadata of a docker CLI context stored in <config dir>/contexts/meta/<digest>/meta.json
+type dockerContextMeta struct {
+	Name      string
+	Endpoints map[string]dockerContextEndpoint
+}
+
+type dockerContextEndpoint struct {
+	Host          string
+	SkipTLSVerify bool
+}
+
+// newAPIClient creates the docker API client and provides the docker host
+func newAPIClient(config ClientConfig) (*client.Client, string, error) {
+	host := config.Host
+	tlsConfig := config.TLSConfig
+	if host == "" && os.Getenv("DOCKER_HOST") == "" {
+		contextHost, contextTLSConfig, err := getDockerContextEndpoint(getDockerConfigDir())
+		if err != nil {
+			return nil, "", err
+		}
+		if contextHost != "" {
+			host = contextHost
+			if tlsConfig == nil {
+				tlsConfig = contextTLSConfig
+			}
+		} else {
+			host = getRootlessDockerHost()
+		}
+	}
+	// the environment configures the client, the fields set in the config override it
+	opts := []client.Opt{client.FromEnv}
+	httpClient := config.HTTPClient
+	if httpClient == nil && tlsConfig != nil {
+		httpClient = &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	}
+	if httpClient != nil {
+		opts = append(opts, client.WithHTTPClient(httpClient))
+	}
+	if host == "" {
+		host = dockerHost()
+	}
+	if _, _, err := parseDockerHost(host); err != nil {
+		return nil, "", err
+	}
+	// configures the transport of the http client for the host
+	opts = append(opts, client.WithHost(host))
+	if config.APIVersion != "" {
+		// a pinned version is not negotiated
+		opts = append(opts, client.WithVersion(config.APIVersion))
+	}
+	cli, err := client.NewClientWithOpts(opts...)
+	return cli, host, err
+}
+
+func parseDockerHost(host string) (string, string, error) {
+	parts := strings.SplitN(host, "://", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("Docker host '%s' is invalid, expected proto://address", host)
+	}
+	return parts[0], parts[1], nil
+}
+
+// getDockerContextEndpoint provides the docker endpoint of the docker CLI context selected by DOCKER_CONTEXT or
+// the currentContext of the docker config. The host is empty, if the default context is used.
+func getDockerContextEndpoint(configDir string) (string, *tls.Config, error) {
+	name := os.Getenv("DOCKER_CONTEXT")
+	if name == "" {
+		config, err := loadDockerConfigFile(configDir)
+		if err != nil {
+			return "", nil, err
+		}
+		name = config.CurrentContext
+	}
+	if name == "" || name == defaultDockerContext || configDir == "" {
+		return "", nil, nil
+	}
+	digest := sha256.Sum256([]byte(name))
+	id := hex.EncodeToString(digest[:])
+
+	metaFile := filepath.Join(configDir, "contexts", "meta", id, "meta.json")
+	data, err := ioutil.ReadFile(metaFile)
+	if err != nil {
+		return "", nil, fmt.Errorf("Docker context '%s' not found: %v", name, err)
+	}
+	var meta dockerContextMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return "", nil, fmt.Errorf("Docker context %s is invalid: %v", metaFile, err)
+	}
+	endpoint, ok := meta.Endpoints["docker"]
+	if !ok || endpoint.Host == "" {
+		return "", nil, fmt.Errorf("Docker context '%s' has no docker endpoint", name)
+	}
+
+	tlsDir := filepath.Join(configDir, "contexts", "tls", id, "docker")
+	options := tlsconfig.Options{InsecureSkipVerify: endpoint.SkipTLSVerify}
+	hasTLSFiles := false
+	for file, option := range map[string]*string{"ca.pem": &options.CAFile, "cert.pem": &options.CertFile, "key.pem": &options.KeyFile} {
+		path := filepath.Join(tlsDir, file)
+		if _, err := os.Stat(path); err == nil {
+			*option = path
+			hasTLSFiles = true
+		}
+	}
+	if !hasTLSFiles && !endpoint.SkipTLSVerify {
+		return endpoint.Host, nil, nil
+	}
+	tlsConfig, err := tlsconfig.Client(options)
+	if err != nil {
+		return "", nil, fmt.Errorf("Docker context '%s' TLS configuration is invalid: %v", name, err)
+	}
+	return endpoint.Host, tlsConfig, nil
+}
+
+// getRootlessDockerHost provides the socket of the rootless docker daemon, if the root daemon socket does not exist
+func getRootlessDockerHost() string {
+	runtimeDir := os.Getenv("XDG_RUNTIME_DIR")
+	if runtimeDir == "" {
+		return ""
+	}
+	if _, err := os.Stat(defaultDockerSocket); err == nil {
+		return ""
+	}
+	socket := filepath.Join(runtimeDir, "docker.sock")
+	if _, err := os.Stat(socket); err != nil {
+		return ""
+	}
+	return "unix://" + socket
+}
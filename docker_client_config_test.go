@@ -0,0 +1,169 @@
+package dockerit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setenv sets the environment variable and provides the function restoring its previous value
+func setenv(name string, value string) func() {
+	previous, ok := os.LookupEnv(name)
+	os.Setenv(name, value)
+	return func() {
+		if ok {
+			os.Setenv(name, previous)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+}
+
+// writeTLSFiles writes a self-signed certificate as ca.pem, cert.pem and key.pem
+func writeTLSFiles(t *testing.T, dir string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "docker-it"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+	for file, data := range map[string][]byte{"ca.pem": certPEM, "cert.pem": certPEM, "key.pem": keyPEM} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeDockerContext(t *testing.T, configDir string, name string, meta string) {
+	digest := sha256.Sum256([]byte(name))
+	dir := filepath.Join(configDir, "contexts", "meta", hex.EncodeToString(digest[:]))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.json"), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetDockerContextEndpoint(t *testing.T) {
+	a := assert.New(t)
+
+	configDir, err := ioutil.TempDir("", "docker-config")
+	a.Nil(err)
+	defer os.RemoveAll(configDir)
+
+	// no config file
+	host, tlsConfig, err := getDockerContextEndpoint(configDir)
+	a.Nil(err)
+	a.Equal("", host)
+	a.Nil(tlsConfig)
+
+	a.Nil(ioutil.WriteFile(filepath.Join(configDir, "config.json"), []byte(`{"currentContext": "colima"}`), 0644))
+	writeDockerContext(t, configDir, "colima", `{"Name": "colima", "Endpoints": {"docker": {"Host": "unix:///home/it/.colima/docker.sock"}}}`)
+	host, tlsConfig, err = getDockerContextEndpoint(configDir)
+	a.Nil(err)
+	a.Equal("unix:///home/it/.colima/docker.sock", host)
+	a.Nil(tlsConfig)
+
+	defer setenv("DOCKER_CONTEXT", "default")()
+	host, _, err = getDockerContextEndpoint(configDir)
+	a.Nil(err)
+	a.Equal("", host)
+
+	os.Setenv("DOCKER_CONTEXT", "remote")
+	_, _, err = getDockerContextEndpoint(configDir)
+	a.NotNil(err)
+	a.Contains(err.Error(), "Docker context 'remote' not found")
+
+	writeDockerContext(t, configDir, "remote", `{"Name": "remote", "Endpoints": {}}`)
+	_, _, err = getDockerContextEndpoint(configDir)
+	a.EqualError(err, "Docker context 'remote' has no docker endpoint")
+}
+
+func TestGetRootlessDockerHost(t *testing.T) {
+	a := assert.New(t)
+
+	runtimeDir, err := ioutil.TempDir("", "runtime")
+	a.Nil(err)
+	defer os.RemoveAll(runtimeDir)
+
+	defer func(socket string) { defaultDockerSocket = socket }(defaultDockerSocket)
+	defaultDockerSocket = filepath.Join(runtimeDir, "missing.sock")
+	defer setenv("XDG_RUNTIME_DIR", runtimeDir)()
+
+	a.Equal("", getRootlessDockerHost())
+
+	socket := filepath.Join(runtimeDir, "docker.sock")
+	a.Nil(ioutil.WriteFile(socket, nil, 0600))
+	a.Equal("unix://"+socket, getRootlessDockerHost())
+
+	// the root daemon is preferred
+	defaultDockerSocket = socket
+	a.Equal("", getRootlessDockerHost())
+}
+
+func TestNewAPIClientTLSFromEnv(t *testing.T) {
+	a := assert.New(t)
+
+	certDir, err := ioutil.TempDir("", "docker-certs")
+	a.Nil(err)
+	defer os.RemoveAll(certDir)
+	writeTLSFiles(t, certDir)
+
+	defer setenv("DOCKER_HOST", "tcp://127.0.0.1:2376")()
+	defer setenv("DOCKER_TLS_VERIFY", "1")()
+	defer setenv("DOCKER_CERT_PATH", certDir)()
+
+	// only the API version is set, the TLS configuration is taken from the environment
+	cli, host, err := newAPIClient(ClientConfig{APIVersion: "1.40"})
+	a.Nil(err)
+	defer cli.Close()
+	a.Equal("tcp://127.0.0.1:2376", host)
+	a.Equal("1.40", cli.ClientVersion())
+	transport, ok := cli.HTTPClient().Transport.(*http.Transport)
+	a.True(ok)
+	a.NotNil(transport.TLSClientConfig)
+}
+
+func TestParseDockerHost(t *testing.T) {
+	a := assert.New(t)
+
+	proto, addr, err := parseDockerHost("tcp://10.0.0.1:2376")
+	a.Nil(err)
+	a.Equal("tcp", proto)
+	a.Equal("10.0.0.1:2376", addr)
+
+	_, _, err = parseDockerHost("10.0.0.1:2376")
+	a.EqualError(err, "Docker host '10.0.0.1:2376' is invalid, expected proto://address")
+}
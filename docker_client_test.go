@@ -9,6 +9,7 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 )
 
 const (
@@ -16,10 +17,13 @@ const (
 )
 
 func TestDockerCommands(t *testing.T) {
+	skipWithoutDaemon(t)
 	a := assert.New(t)
 
-	dc, err := newDockerClient()
-	a.Nil(err)
+	dc, err := newDockerClient(ClientConfig{})
+	if !a.Nil(err) {
+		return
+	}
 
 	_, err = dc.GetImageByName(testImage)
 	a.Nil(err)
@@ -28,10 +32,10 @@ func TestDockerCommands(t *testing.T) {
 	a.Nil(sum)
 	a.Nil(err)
 
-	err = dc.PullImage(testImage)
+	err = dc.PullImage(testImage, "", "", nil)
 	a.Nil(err)
 
-	err = dc.PullImage("this_image_does_not_exist")
+	err = dc.PullImage("this_image_does_not_exist", "", "", nil)
 
 	exposedPorts := make(nat.PortSet)
 	port, err := nat.NewPort("tcp", strconv.Itoa(4771))
@@ -51,7 +55,7 @@ func TestDockerCommands(t *testing.T) {
 	cmd := []string{}
 	binds := []string{}
 	dnsServer := ""
-	containerID, err := dc.CreateContainer(containerName, testImage, env, portSpecs, cmd, binds, dnsServer)
+	containerID, err := dc.CreateContainer(ContainerConfig{Name: containerName, Image: testImage, Env: env, PortSpecs: portSpecs, Cmd: cmd, Binds: binds, DNSServer: dnsServer})
 	a.Nil(err)
 
 	container, err := dc.GetContainerByID(containerID)
@@ -68,24 +72,24 @@ func TestDockerCommands(t *testing.T) {
 	err = dc.StartContainer(containerID)
 	a.Nil(err)
 
-	reader, err := dc.ContainerLogs(containerID, false)
+	reader, err := dc.ContainerLogs(containerID, false, false, time.Time{})
 	a.Nil(err)
 	_, err = stdcopy.StdCopy(os.Stdout, os.Stderr, reader)
 
-	err = dc.StopContainer(containerID)
+	err = dc.StopContainer(containerID, nil)
 	a.Nil(err)
 
-	err = dc.StopContainer(containerID)
+	err = dc.StopContainer(containerID, nil)
 	a.Nil(err)
 
-	reader, err = dc.ContainerLogs(containerID, false)
+	reader, err = dc.ContainerLogs(containerID, false, false, time.Time{})
 	a.Nil(err)
 	_, err = stdcopy.StdCopy(os.Stdout, os.Stderr, reader)
 
 	err = dc.RemoveContainer(containerID)
 	a.Nil(err)
 
-	_, err = dc.ContainerLogs(containerID, false)
+	_, err = dc.ContainerLogs(containerID, false, false, time.Time{})
 	a.NotNil(err)
 
 	err = dc.RemoveImageByName(testImage)
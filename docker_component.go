@@ -1,13 +1,33 @@
 package dockerit
 
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
 // DockerComponent holds parameters defining docker component.
+// String fields except Name are templates resolved with the environment values, see DockerEnvironment.Values.
 type DockerComponent struct {
 	// Name of the docker component
 	Name string
 	// Docker image name
 	Image string
-	// Pull an image from a registry
+	// Pull an image from a registry.
+	//
+	// Deprecated: use PullPolicy. ForcePull is used only if PullPolicy is not set and means PullAlways,
+	// otherwise PullNever.
 	ForcePull bool
+	// Defines when the image is pulled from a registry
+	PullPolicy PullPolicy
+	// Credentials used to pull the image. If not specified, the credentials are read from the docker config
+	// ($DOCKER_CONFIG/config.json or ~/.docker/config.json) and its credential helpers.
+	RegistryAuth *RegistryAuth
+	// Required image platform in format os[/arch[/variant]], e.g. linux/arm64. It is passed to the image pull and the container create.
+	Platform string
+	// Path to an image archive created by docker save, see DockerEnvironment.SaveImages.
+	// The archive is loaded when the image does not exist on the docker host.
+	ImageArchive string
 	// After destroy remove image from the docker host
 	RemoveImageAfterDestroy bool
 	// List of exposed ports
@@ -16,22 +36,180 @@ type DockerComponent struct {
 	EnvironmentVariables map[string]string
 	// Command to run when starting the container
 	Cmd []string
+	// Entrypoint of the container. If not specified, the entrypoint of the image is used.
+	Entrypoint []string
 	// List of volume bindings for this container
 	Binds []string
 	// DNS server to lookup
 	DNSServer string
 	// Follow container log output
 	FollowLogs bool
-	// Callback invoked after start container command was invoked.
+	// Consumer of the container log output. If not specified, stdout and stderr are written to stdout
+	// with the component name as prefix.
+	LogConsumer LogConsumer
+	// Prefix each line of the container log output with the timestamp provided by docker
+	LogTimestamps bool
+	// Number of the last lines of the followed log output kept in memory. If not specified, 1000 lines are kept.
+	LogBufferSize int
+	// Number of the last lines of the container log output attached to a StartError. If not specified, 50 lines are attached.
+	ErrorLogLines int
+	// Container health check. If specified, Start waits until the container is healthy before AfterStart is invoked.
+	Healthcheck *Healthcheck
+	// Callback invoked after start container command was invoked and the container is healthy.
+	// It is invoked before the Hooks.AfterStart hooks.
 	AfterStart Callback
+	// Hooks invoked in the lifecycle phases of the component
+	Hooks Hooks
+}
+
+// Hooks holds the lifecycle hooks of a docker component. The hooks of a phase are invoked in the order of the list.
+// A failed hook of BeforeCreate, BeforeStart or AfterStart fails the start of the component, the remaining hooks of
+// the phase are skipped. All stop and destroy hooks are invoked and the container is stopped or removed even if a hook
+// failed, the first error is returned.
+type Hooks struct {
+	// Invoked before the container is created, the container ID is empty
+	BeforeCreate []Hook
+	// Invoked before the container is started
+	BeforeStart []Hook
+	// Invoked after the container was started, the container is healthy and the DockerComponent.AfterStart callback succeeded
+	AfterStart []Hook
+	// Invoked before the running container is stopped
+	BeforeStop []Hook
+	// Invoked after the container was stopped
+	AfterStop []Hook
+	// Invoked before the existing container is removed
+	BeforeDestroy []Hook
+}
+
+// Hook is invoked in a lifecycle phase of a docker component
+type Hook interface {
+	// Call is invoked with the component name, the value resolver and the component container
+	Call(componentName string, resolver ValueResolver, component Component) error
 }
 
+// HookFunc is an adapter to use a function as Hook
+type HookFunc func(componentName string, resolver ValueResolver, component Component) error
+
+// Call implements Hook
+func (f HookFunc) Call(componentName string, resolver ValueResolver, component Component) error {
+	return f(componentName, resolver, component)
+}
+
+// Component provides access to the container of a docker component
+type Component interface {
+	// Name returns the component name
+	Name() string
+	// ContainerID returns the ID of the container, empty if the container was not created
+	ContainerID() string
+	// Exec runs a command in the running container and returns the exit code of the command
+	Exec(cmd []string, stdout, stderr io.Writer) (int, error)
+	// WriteLogs writes the log output of the container
+	WriteLogs(stdout, stderr io.Writer) error
+	// Logs provides the stdout and stderr lines of the container logged not before since. Use zero since to get all lines.
+	Logs(since time.Time) ([]string, error)
+	// Inspect provides the current state of the container
+	Inspect() (ContainerState, error)
+	// Context provides the value resolver of the environment
+	Context() ValueResolver
+}
+
+// ContainerState describes the state of a component container
+type ContainerState struct {
+	// Container status, e.g. "created", "running" or "exited"
+	Status string
+	// Whether the container is running
+	Running bool
+	// Exit code of the stopped container
+	ExitCode int
+	// Whether the container was killed because it ran out of memory
+	OOMKilled bool
+	// Health status "starting", "healthy" or "unhealthy", empty if the container has no health check
+	Health string
+	// Error message of the container start
+	Error string
+}
+
+// Healthcheck holds the docker health check of a container
+type Healthcheck struct {
+	// Test to perform, e.g. ["CMD", "pg_isready"] or ["CMD-SHELL", "curl -f http://localhost"].
+	// ["NONE"] disables the health check of the image.
+	Test []string
+	// Time between running the check. If not specified, the docker default is used.
+	Interval time.Duration
+	// Time to wait before considering the check to have hung. If not specified, the docker default is used.
+	Timeout time.Duration
+	// Number of consecutive failures needed to consider a container as unhealthy.
+	// If not specified, the docker default is used.
+	Retries int
+	// Start period for the container to initialize before the failed checks count towards the retries.
+	StartPeriod time.Duration
+}
+
+func (r *Healthcheck) disabled() bool {
+	return len(r.Test) == 1 && r.Test[0] == "NONE"
+}
+
+// waitTimeout is the time after which docker reports the container as unhealthy if none of the checks succeeded
+func (r *Healthcheck) waitTimeout() time.Duration {
+	interval, timeout, retries := r.Interval, r.Timeout, r.Retries
+	if interval <= 0 {
+		interval = defaultHealthcheckInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultHealthcheckTimeout
+	}
+	if retries <= 0 {
+		retries = defaultHealthcheckRetries
+	}
+	return r.StartPeriod + time.Duration(retries+1)*(interval+timeout)
+}
+
+// PullPolicy defines when the image of a component is pulled from a registry
+type PullPolicy string
+
+const (
+	// PullAlways pulls the image before the container is created. If the pull fails, an existing local image is used.
+	PullAlways PullPolicy = "Always"
+	// PullIfNotPresent pulls the image only if it does not exist on the docker host
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+	// PullNever requires the image to exist on the docker host
+	PullNever PullPolicy = "Never"
+)
+
 // Callback provides a way for the callee to invoke the code inside the caller
 type Callback interface {
 	// Callback method invoked with the current component name and value resolver
 	Call(componentName string, resolver ValueResolver) error
 }
 
+// ComponentCallback is a Callback with access to the component container. If the AfterStart callback implements
+// ComponentCallback, CallComponent is invoked instead of Call.
+type ComponentCallback interface {
+	Callback
+	// CallComponent is invoked with the started component
+	CallComponent(component Component) error
+}
+
+// ComponentCallbackFunc is an adapter to use a function as ComponentCallback
+type ComponentCallbackFunc func(component Component) error
+
+// CallComponent implements ComponentCallback
+func (f ComponentCallbackFunc) CallComponent(component Component) error {
+	return f(component)
+}
+
+// Call implements Callback, the function requires the component and cannot be invoked without it
+func (f ComponentCallbackFunc) Call(componentName string, resolver ValueResolver) error {
+	return fmt.Errorf("DockerComponent [%s] callback requires the component container", componentName)
+}
+
+// LogConsumer receives the log output of a docker component
+type LogConsumer interface {
+	// Writers is invoked before the container log output is copied and provides writers for stdout and stderr.
+	// Writers implementing io.Closer are closed when the log output ends.
+	Writers(componentName string) (stdout io.Writer, stderr io.Writer, err error)
+}
+
 // ValueResolver allows resolution of container parameters
 type ValueResolver interface {
 	// Resolve applies a parsed template to the docker environment context
@@ -42,6 +220,18 @@ type ValueResolver interface {
 	Port(componentName string, portName string) (int, error)
 }
 
+// ComponentStatus describes the container of a docker component
+type ComponentStatus struct {
+	// Name of the docker component
+	Name string
+	// Container ID, empty if the container was not created
+	ContainerID string
+	// Container state e.g. "running, health healthy" or "exited, exit code 1"
+	State string
+	// Port bindings with the host ports
+	Ports []Port
+}
+
 // Port holds definition of a port mapping
 type Port struct {
 	// Optional port name. If not specified, the lower-cased component name is used.
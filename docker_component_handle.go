@@ -0,0 +1,48 @@
+package dockerit
+
+import (
+	"io"
+	"time"
+)
+
+// dockerComponentHandle provides the container of a docker component to hooks
+type dockerComponentHandle struct {
+	container        *dockerContainer
+	lifecycleHandler *dockerLifecycleHandler
+}
+
+// implements Component
+func (r *dockerComponentHandle) Name() string {
+	return r.container.Name
+}
+
+// implements Component
+func (r *dockerComponentHandle) ContainerID() string {
+	containerID, _, _ := r.container.runtimeValues()
+	return containerID
+}
+
+// implements Component
+func (r *dockerComponentHandle) Exec(cmd []string, stdout, stderr io.Writer) (int, error) {
+	return r.lifecycleHandler.Exec(r.container, cmd, stdout, stderr)
+}
+
+// implements Component
+func (r *dockerComponentHandle) WriteLogs(stdout, stderr io.Writer) error {
+	return r.lifecycleHandler.WriteLogs(r.container, stdout, stderr)
+}
+
+// implements Component
+func (r *dockerComponentHandle) Logs(since time.Time) ([]string, error) {
+	return r.lifecycleHandler.Logs(r.container, since)
+}
+
+// implements Component
+func (r *dockerComponentHandle) Inspect() (ContainerState, error) {
+	return r.lifecycleHandler.Inspect(r.container)
+}
+
+// implements Component
+func (r *dockerComponentHandle) Context() ValueResolver {
+	return r.lifecycleHandler.context
+}
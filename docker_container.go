@@ -1,17 +1,94 @@
 package dockerit
 
+import (
+	"sync"
+	"time"
+)
+
 type dockerContainer struct {
 	DockerComponent
+	// component fields with resolved templates
+	resolved resolvedComponent
 
+	// guards the writes of containerID, containerName and ip, which are read by the templates of other components
+	runtimeMutex sync.RWMutex
 	containerID  string
-	portBindings []Port
-	env          map[string]string
+	// name and IP of the created container
+	containerName string
+	ip            string
+	// set to 1 when the environment stops or destroys the container
+	stopRequested int32
+	// durations of the lifecycle phases
+	phaseDurations phaseDurations
+	portBindings   []Port
+	env            map[string]string
 
 	stopFollowLogsChannel chan struct{}
+	logBuffer             *logBuffer
+	// container and start time of the last followed log output
+	followedContainerID string
+	startedAt           time.Time
+}
+
+// resolvedComponent holds the component fields after template resolution
+type resolvedComponent struct {
+	image        string
+	platform     string
+	imageArchive string
+	registryAuth *RegistryAuth
+	cmd          []string
+	entrypoint   []string
+	binds        []string
+	dnsServer    string
+	healthcheck  *Healthcheck
 }
 
 func newDockerContainer(component DockerComponent) *dockerContainer {
-	return &dockerContainer{DockerComponent: component, stopFollowLogsChannel: make(chan struct{}, 1)}
+	container := &dockerContainer{
+		DockerComponent: component,
+		// templates are resolved when the environment is configured
+		resolved: resolvedComponent{
+			image:        component.Image,
+			platform:     component.Platform,
+			imageArchive: component.ImageArchive,
+			registryAuth: component.RegistryAuth,
+			cmd:          component.Cmd,
+			entrypoint:   component.Entrypoint,
+			binds:        component.Binds,
+			dnsServer:    component.DNSServer,
+			healthcheck:  component.Healthcheck,
+		},
+		stopFollowLogsChannel: make(chan struct{}, 1),
+	}
+	if component.FollowLogs {
+		container.logBuffer = newLogBuffer(component.LogBufferSize)
+	}
+	return container
+}
+
+// setContainer sets the created container, the container ID and name are empty after the container is removed
+func (r *dockerContainer) setContainer(containerID string, containerName string) {
+	r.runtimeMutex.Lock()
+	defer r.runtimeMutex.Unlock()
+	r.containerID = containerID
+	r.containerName = containerName
+	if containerID == "" {
+		r.ip = ""
+	}
+}
+
+func (r *dockerContainer) setIP(ip string) {
+	r.runtimeMutex.Lock()
+	defer r.runtimeMutex.Unlock()
+	r.ip = ip
+}
+
+// runtimeValues provides the container ID, name and IP. The values are written only by the goroutine
+// operating the container, other goroutines must read them with this method.
+func (r *dockerContainer) runtimeValues() (string, string, string) {
+	r.runtimeMutex.RLock()
+	defer r.runtimeMutex.RUnlock()
+	return r.containerID, r.containerName, r.ip
 }
 
 func (r *dockerContainer) stopFollowLogs() {
@@ -20,3 +97,37 @@ func (r *dockerContainer) stopFollowLogs() {
 	default:
 	}
 }
+
+func (r *dockerContainer) getLogConsumer() LogConsumer {
+	if r.LogConsumer != nil {
+		return r.LogConsumer
+	}
+	return stdoutLogConsumer{}
+}
+
+func (r *dockerContainer) getPullPolicy() PullPolicy {
+	if r.PullPolicy != "" {
+		return r.PullPolicy
+	}
+	if r.ForcePull {
+		return PullAlways
+	}
+	return PullNever
+}
+
+// imageOptions holds the options used to check, load or pull an image
+type imageOptions struct {
+	pullPolicy   PullPolicy
+	registryAuth *RegistryAuth
+	platform     string
+	archive      string
+}
+
+func (r *dockerContainer) getImageOptions() imageOptions {
+	return imageOptions{
+		pullPolicy:   r.getPullPolicy(),
+		registryAuth: r.resolved.registryAuth,
+		platform:     r.resolved.platform,
+		archive:      r.resolved.imageArchive,
+	}
+}
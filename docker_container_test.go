@@ -36,3 +36,11 @@ func TestStopFollowLogsIsNonBlocking(t *testing.T) {
 		container.stopFollowLogs()
 	}
 }
+
+func TestPullPolicy(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(PullNever, newDockerContainer(DockerComponent{Name: "it-redis", Image: "redis"}).getPullPolicy())
+	a.Equal(PullAlways, newDockerContainer(DockerComponent{Name: "it-redis", Image: "redis", ForcePull: true}).getPullPolicy())
+	a.Equal(PullIfNotPresent, newDockerContainer(DockerComponent{Name: "it-redis", Image: "redis", ForcePull: true, PullPolicy: PullIfNotPresent}).getPullPolicy())
+}
@@ -0,0 +1,114 @@
+package dockerit
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/client"
+	"os"
+	"strings"
+)
+
+const (
+	// minAPIVersion is the oldest docker API version supported, docker 1.13
+	minAPIVersion = "1.25"
+)
+
+// DaemonErrorReason describes why the docker daemon cannot be used
+type DaemonErrorReason string
+
+const (
+	// DaemonUnreachable means no docker daemon is listening on the docker host
+	DaemonUnreachable DaemonErrorReason = "unreachable"
+	// DaemonPermissionDenied means the docker socket cannot be accessed by the current user
+	DaemonPermissionDenied DaemonErrorReason = "permission denied"
+	// DaemonVersionTooOld means the API version of the docker daemon is not supported
+	DaemonVersionTooOld DaemonErrorReason = "version too old"
+)
+
+// DaemonError is returned when the docker daemon cannot be used
+type DaemonError struct {
+	// Reason of the failure
+	Reason DaemonErrorReason
+	// Docker host, e.g. unix:///var/run/docker.sock
+	Host string
+	// API version of the docker daemon, set if the version is too old
+	APIVersion string
+	// Error causing the failure
+	Err error
+}
+
+// implements error
+func (e *DaemonError) Error() string {
+	switch e.Reason {
+	case DaemonPermissionDenied:
+		return fmt.Sprintf("Docker daemon at %s: permission denied, add the user to the docker group or set DOCKER_HOST: %v", e.Host, e.Err)
+	case DaemonVersionTooOld:
+		return fmt.Sprintf("Docker daemon at %s: API version %s is too old, at least %s is required", e.Host, e.APIVersion, minAPIVersion)
+	default:
+		return fmt.Sprintf("Docker daemon at %s is unreachable, is the docker daemon running? %v", e.Host, e.Err)
+	}
+}
+
+// Cause returns the error causing the failure
+func (e *DaemonError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error causing the failure, see errors.Is and errors.As
+func (e *DaemonError) Unwrap() error {
+	return e.Err
+}
+
+// Available checks if the docker daemon is reachable and supported, e.g. to skip tests
+func Available() bool {
+	return AvailableWithClientConfig(ClientConfig{})
+}
+
+// AvailableWithClientConfig checks if the docker daemon of the docker client configuration is reachable and supported
+func AvailableWithClientConfig(config ClientConfig) bool {
+	return checkDaemon(config) == nil
+}
+
+// checkDaemon connects the docker daemon
+func checkDaemon(config ClientConfig) error {
+	dockerClient, err := newDockerClient(config)
+	if err != nil {
+		return err
+	}
+	return dockerClient.Close()
+}
+
+// negotiateAPIVersion pings the docker daemon and downgrades the client API version to the daemon API version,
+// unless the version is pinned
+func negotiateAPIVersion(cli *client.Client, host string, pinned bool) error {
+	ping, err := cli.Ping(context.Background())
+	if err != nil {
+		return newDaemonError(host, err)
+	}
+	if ping.APIVersion == "" {
+		return nil
+	}
+	if versions.LessThan(ping.APIVersion, minAPIVersion) {
+		return &DaemonError{Reason: DaemonVersionTooOld, Host: host, APIVersion: ping.APIVersion}
+	}
+	if !pinned {
+		cli.NegotiateAPIVersionPing(ping)
+	}
+	return nil
+}
+
+func newDaemonError(host string, err error) *DaemonError {
+	reason := DaemonUnreachable
+	if strings.Contains(strings.ToLower(err.Error()), "permission denied") {
+		reason = DaemonPermissionDenied
+	}
+	return &DaemonError{Reason: reason, Host: host, Err: err}
+}
+
+func dockerHost() string {
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		return host
+	}
+	return client.DefaultDockerHost
+}
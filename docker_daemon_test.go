@@ -0,0 +1,44 @@
+package dockerit
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewDaemonError(t *testing.T) {
+	a := assert.New(t)
+
+	cause := errors.New("Cannot connect to the Docker daemon. Is the docker daemon running on this host?")
+	err := newDaemonError("unix:///run/user/1000/docker.sock", cause)
+	a.Equal(DaemonUnreachable, err.Reason)
+	a.True(errors.Is(err, cause))
+	a.Equal("unix:///run/user/1000/docker.sock", err.Host)
+	a.Contains(err.Error(), "is unreachable")
+
+	err = newDaemonError("unix:///var/run/docker.sock", errors.New("dial unix /var/run/docker.sock: connect: permission denied"))
+	a.Equal(DaemonPermissionDenied, err.Reason)
+	a.Contains(err.Error(), "permission denied")
+}
+
+func TestDaemonErrorVersionTooOld(t *testing.T) {
+	a := assert.New(t)
+
+	err := &DaemonError{Reason: DaemonVersionTooOld, Host: "tcp://10.0.0.1:2375", APIVersion: "1.24"}
+	a.Equal("Docker daemon at tcp://10.0.0.1:2375: API version 1.24 is too old, at least 1.25 is required", err.Error())
+}
+
+func TestCheckDaemonWithClientConfig(t *testing.T) {
+	a := assert.New(t)
+
+	err := checkDaemon(ClientConfig{Host: "tcp://127.0.0.1:1"})
+	var daemonError *DaemonError
+	if a.True(errors.As(err, &daemonError)) {
+		a.Equal(DaemonUnreachable, daemonError.Reason)
+		a.Equal("tcp://127.0.0.1:1", daemonError.Host)
+	}
+	a.False(AvailableWithClientConfig(ClientConfig{Host: "tcp://127.0.0.1:1"}))
+
+	_, err = PruneWithClientConfig(ClientConfig{Host: "tcp://127.0.0.1:1"}, PruneFilter{DryRun: true})
+	a.True(errors.As(err, &daemonError))
+}
@@ -3,10 +3,14 @@ package dockerit
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"regexp"
+	"sort"
 	"sync"
 	"syscall"
+	"time"
 )
 
 // DockerEnvironment holds defined docker components
@@ -20,11 +24,33 @@ type DockerEnvironment struct {
 
 // NewDockerEnvironment creates a new docker test environment
 func NewDockerEnvironment(components ...DockerComponent) (*DockerEnvironment, error) {
+	return NewDockerEnvironmentWithOptions(nil, components...)
+}
+
+// NewDockerEnvironmentWithID creates a new docker test environment with the given ID, which is appended to the container names.
+// If the ID is empty, a random one is used.
+func NewDockerEnvironmentWithID(id string, components ...DockerComponent) (*DockerEnvironment, error) {
+	return NewDockerEnvironmentWithOptions([]Option{WithID(id)}, components...)
+}
+
+// NewDockerEnvironmentWithClientConfig creates a new docker test environment using the docker client configuration
+func NewDockerEnvironmentWithClientConfig(config ClientConfig, components ...DockerComponent) (*DockerEnvironment, error) {
+	return NewDockerEnvironmentWithOptions([]Option{WithClient(config)}, components...)
+}
+
+// NewDockerEnvironmentWithOptions creates a new docker test environment configured by the options, e.g. WithID or WithNetwork
+func NewDockerEnvironmentWithOptions(opts []Option, components ...DockerComponent) (*DockerEnvironment, error) {
 	if len(components) == 0 {
 		return nil, errors.New("Component list is empty")
 	}
+	var options environmentOptions
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, err
+		}
+	}
 	// new context
-	context, err := newDockerEnvironmentContext()
+	context, err := newDockerEnvironmentContextWithOptions(options)
 	if err != nil {
 		return nil, err
 	}
@@ -61,7 +87,7 @@ func (r *DockerEnvironment) Start(names ...string) error {
 	return r.forEach(r.lifecycleHandler.Start, names...)
 }
 
-// StartParallel starts docker components in parallel
+// StartParallel starts docker components in parallel, at most the number set by WithParallelism at once
 func (r *DockerEnvironment) StartParallel(names ...string) error {
 	if (len(names)) == 0 {
 		return errors.New("No component was provided to start in parallel")
@@ -72,11 +98,18 @@ func (r *DockerEnvironment) StartParallel(names ...string) error {
 	var wg sync.WaitGroup
 	errorChannel := make(chan error, len(names))
 	doneChannel := make(chan struct{}, 1)
+	parallelism := r.context.parallelism
+	if parallelism == 0 {
+		parallelism = len(names)
+	}
+	semaphore := make(chan struct{}, parallelism)
 
 	for _, name := range names {
 		wg.Add(1)
 		go func(name string) {
 			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
 			err := r.Start(name)
 			if err != nil {
 				r.context.logger.Error.Println("Component start error", err)
@@ -97,9 +130,43 @@ func (r *DockerEnvironment) StartParallel(names ...string) error {
 	case <-doneChannel:
 	}
 	r.context.logger.Info.Println("All components started")
+	containers := make([]*dockerContainer, 0, len(names))
+	for _, name := range names {
+		if container, err := r.context.getContainer(name); err == nil {
+			containers = append(containers, container)
+		}
+	}
+	r.logReport(containers)
 	return nil
 }
 
+// ID returns the environment ID
+func (r *DockerEnvironment) ID() string {
+	return r.context.ID
+}
+
+// SetImageRewriter sets the rewrite of image names applied before images are checked, loaded or pulled,
+// e.g. NewRegistryMirrorRewriter. It replaces the registry mirrors given by the DOCKER_IT_REGISTRY_MIRROR
+// environment variable and must be set before any component is started.
+func (r *DockerEnvironment) SetImageRewriter(rewriter ImageRewriter) {
+	r.context.imageRewriter = rewriter
+}
+
+// PullImages pulls or checks the images of all components according to their PullPolicy before any container is created.
+// Distinct images are pulled concurrently and only once.
+func (r *DockerEnvironment) PullImages() error {
+	r.context.logger.Info.Println("Pulling images")
+	return r.lifecycleHandler.PullImages(r.getContainers(), r.context.pullParallelism)
+}
+
+// SaveImages writes the images of all components into the directory as archives created by docker save.
+// The images are pulled or checked first. Use ImageArchivePath to get the path of an image archive.
+// A rewritten image is tagged with the configured name, which is saved in the archive as well.
+func (r *DockerEnvironment) SaveImages(dir string) error {
+	r.context.logger.Info.Println("Saving images to", dir)
+	return r.lifecycleHandler.SaveImages(dir, r.getContainers(), r.context.pullParallelism)
+}
+
 // Stop stops docker components
 func (r *DockerEnvironment) Stop(names ...string) error {
 	return r.forEach(r.lifecycleHandler.Stop, names...)
@@ -123,6 +190,69 @@ func (r *DockerEnvironment) forEach(f func(*dockerContainer) error, names ...str
 	return nil
 }
 
+// Attach adopts the existing containers of the environment ID, e.g. started by another process with NewDockerEnvironmentWithID.
+// The host ports of the components are taken from the containers.
+func (r *DockerEnvironment) Attach() error {
+	for _, container := range r.getContainers() {
+		if err := r.lifecycleHandler.Attach(container); err != nil {
+			return err
+		}
+	}
+	// environment variables can refer to the attached host ports
+	return r.context.configureContainersEnv()
+}
+
+// Status describes the containers of all components
+func (r *DockerEnvironment) Status() ([]ComponentStatus, error) {
+	result := make([]ComponentStatus, 0, len(r.context.containers))
+	for _, container := range r.getContainers() {
+		status, err := r.lifecycleHandler.Status(container)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
+
+// Exec runs a command in the running container of a component and returns the exit code of the command
+func (r *DockerEnvironment) Exec(componentName string, cmd []string, stdout, stderr io.Writer) (int, error) {
+	container, err := r.context.getContainer(componentName)
+	if err != nil {
+		return 0, err
+	}
+	return r.lifecycleHandler.Exec(container, cmd, stdout, stderr)
+}
+
+// WriteLogs writes the log output of a component container
+func (r *DockerEnvironment) WriteLogs(componentName string, stdout, stderr io.Writer) error {
+	container, err := r.context.getContainer(componentName)
+	if err != nil {
+		return err
+	}
+	return r.lifecycleHandler.WriteLogs(container, stdout, stderr)
+}
+
+// DumpLogs writes the state and the log output of all components, e.g. for post-mortem debugging
+func (r *DockerEnvironment) DumpLogs(w io.Writer) error {
+	return r.lifecycleHandler.DumpLogs(w, r.getContainers())
+}
+
+// getContainers provides containers sorted by the component name
+func (r *DockerEnvironment) getContainers() []*dockerContainer {
+	names := make([]string, 0, len(r.context.containers))
+	for name := range r.context.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	containers := make([]*dockerContainer, 0, len(names))
+	for _, name := range names {
+		containers = append(containers, r.context.containers[name])
+	}
+	return containers
+}
+
 // Close closes docker environment lifecycle handle
 func (r *DockerEnvironment) Close() {
 	r.lifecycleHandler.Close()
@@ -143,6 +273,76 @@ func (r *DockerEnvironment) Port(componentName string, portName string) (int, er
 	return r.context.Port(componentName, portName)
 }
 
+// Logs provides the buffered log output lines of a component logged not before since. Use zero since to get all buffered lines.
+// The log output is buffered only for components with FollowLogs set.
+func (r *DockerEnvironment) Logs(componentName string, since time.Time) ([]string, error) {
+	buffer, err := r.getLogBuffer(componentName)
+	if err != nil {
+		return nil, err
+	}
+	lines, _ := buffer.lines(since)
+	return lines, nil
+}
+
+// LogsContain checks if any buffered log output line of a component matches the regular expression
+func (r *DockerEnvironment) LogsContain(componentName string, expr string) (bool, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return false, err
+	}
+	buffer, err := r.getLogBuffer(componentName)
+	if err != nil {
+		return false, err
+	}
+	lines, _ := buffer.lines(time.Time{})
+	return matchAny(re, lines), nil
+}
+
+// WaitForLog waits until any buffered log output line of a component matches the regular expression or timeout is reached
+func (r *DockerEnvironment) WaitForLog(componentName string, expr string, timeout time.Duration) error {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	buffer, err := r.getLogBuffer(componentName)
+	if err != nil {
+		return err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		lines, appended := buffer.lines(time.Time{})
+		if matchAny(re, lines) {
+			return nil
+		}
+		select {
+		case <-appended:
+		case <-timer.C:
+			return fmt.Errorf("Log output of '%s' did not match '%s' within %s", componentName, expr, timeout)
+		}
+	}
+}
+
+func (r *DockerEnvironment) getLogBuffer(componentName string) (*logBuffer, error) {
+	container, err := r.context.getContainer(componentName)
+	if err != nil {
+		return nil, err
+	}
+	if container.logBuffer == nil {
+		return nil, fmt.Errorf("DockerComponent [%s] does not follow logs", componentName)
+	}
+	return container.logBuffer, nil
+}
+
+func matchAny(re *regexp.Regexp, lines []string) bool {
+	for _, line := range lines {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
 // WithShutdown registers callback invoked after SIGINT or SIGTERM were received
 func (r *DockerEnvironment) WithShutdown(beforeShutdown ...func()) chan struct{} {
 	doneChannel := make(chan struct{}, 1)
@@ -184,6 +384,9 @@ func (r *DockerEnvironment) Shutdown(beforeShutdown ...func()) {
 				r.context.logger.Error.Println("Destroy component error", err)
 			}
 		}
+		if err := r.lifecycleHandler.RemoveNetwork(); err != nil {
+			r.context.logger.Error.Println("Remove network error", err)
+		}
 		r.lifecycleHandler.Close()
 	})
 }
@@ -0,0 +1,144 @@
+package dockerit
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type composeFile struct {
+	Name     string                    `yaml:"name"`
+	Services map[string]composeService `yaml:"services"`
+	Volumes  map[string]composeVolume  `yaml:"volumes,omitempty"`
+}
+
+type composeService struct {
+	Image       string                       `yaml:"image"`
+	Platform    string                       `yaml:"platform,omitempty"`
+	Environment map[string]string            `yaml:"environment,omitempty"`
+	Ports       []string                     `yaml:"ports,omitempty"`
+	Command     []string                     `yaml:"command,omitempty"`
+	Entrypoint  []string                     `yaml:"entrypoint,omitempty"`
+	Volumes     []string                     `yaml:"volumes,omitempty"`
+	DNS         []string                     `yaml:"dns,omitempty"`
+	Healthcheck *composeHealthcheck          `yaml:"healthcheck,omitempty"`
+	DependsOn   map[string]composeDependency `yaml:"depends_on,omitempty"`
+}
+
+type composeDependency struct {
+	Condition string `yaml:"condition"`
+}
+
+type composeHealthcheck struct {
+	Test        []string `yaml:"test"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
+}
+
+type composeVolume struct {
+	Name string `yaml:"name"`
+}
+
+var invalidComposeProjectNameChars = regexp.MustCompile(`[^a-z0-9_-]`)
+
+// ComposeProjectName applies the docker compose project name rules to a name, e.g. an environment ID
+func ComposeProjectName(name string) string {
+	name = invalidComposeProjectNameChars.ReplaceAllString(strings.ToLower(name), "")
+	return strings.TrimLeft(name, "_-")
+}
+
+// newComposeFile converts the containers in the start order with the resolved environment variables and host ports.
+// Each service depends on the previous one, which must be healthy if it has a health check.
+func newComposeFile(id string, containers []*dockerContainer, rewriteImage ImageRewriter) (*composeFile, error) {
+	compose := &composeFile{
+		Name:     ComposeProjectName(id),
+		Services: make(map[string]composeService),
+	}
+	var previous *dockerContainer
+	for _, container := range containers {
+		if container.portBindings == nil {
+			return nil, fmt.Errorf("portBindings for '%s' is not defined", container.Name)
+		}
+		service := composeService{
+			Image:       escapeCompose(rewriteImage(container.resolved.image)),
+			Platform:    container.resolved.platform,
+			Environment: make(map[string]string),
+		}
+		if previous != nil {
+			condition := "service_started"
+			if healthcheck := previous.resolved.healthcheck; healthcheck != nil && len(healthcheck.Test) != 0 {
+				condition = "service_healthy"
+			}
+			service.DependsOn = map[string]composeDependency{previous.Name: {Condition: condition}}
+		}
+		previous = container
+		for k, v := range container.env {
+			service.Environment[k] = escapeCompose(v)
+		}
+		for _, port := range container.portBindings {
+			service.Ports = append(service.Ports, fmt.Sprintf("%d:%d", port.HostPort, port.ContainerPort))
+		}
+		for _, arg := range container.resolved.cmd {
+			service.Command = append(service.Command, escapeCompose(arg))
+		}
+		for _, arg := range container.resolved.entrypoint {
+			service.Entrypoint = append(service.Entrypoint, escapeCompose(arg))
+		}
+		for _, bind := range container.resolved.binds {
+			service.Volumes = append(service.Volumes, escapeCompose(bind))
+			if source := strings.SplitN(bind, ":", 2)[0]; !filepath.IsAbs(source) {
+				// named volume
+				if compose.Volumes == nil {
+					compose.Volumes = make(map[string]composeVolume)
+				}
+				compose.Volumes[source] = composeVolume{Name: source}
+			}
+		}
+		if container.resolved.dnsServer != "" {
+			service.DNS = []string{container.resolved.dnsServer}
+		}
+		if healthcheck := container.resolved.healthcheck; healthcheck != nil {
+			service.Healthcheck = &composeHealthcheck{Test: healthcheck.Test, Retries: healthcheck.Retries}
+			if healthcheck.Interval != 0 {
+				service.Healthcheck.Interval = healthcheck.Interval.String()
+			}
+			if healthcheck.Timeout != 0 {
+				service.Healthcheck.Timeout = healthcheck.Timeout.String()
+			}
+			if healthcheck.StartPeriod != 0 {
+				service.Healthcheck.StartPeriod = healthcheck.StartPeriod.String()
+			}
+		}
+		compose.Services[container.Name] = service
+	}
+	return compose, nil
+}
+
+// escapeCompose prevents the variable substitution of compose
+func escapeCompose(value string) string {
+	return strings.Replace(value, "$", "$$", -1)
+}
+
+// ExportCompose writes a docker-compose file reproducing the configured components with the resolved
+// environment variables and the chosen host ports. The services are started in the configured order.
+func (r *DockerEnvironment) ExportCompose(w io.Writer) error {
+	containers := make([]*dockerContainer, 0, len(r.context.names))
+	for _, name := range r.context.names {
+		containers = append(containers, r.context.containers[name])
+	}
+	compose, err := newComposeFile(r.context.ID, containers, r.context.rewriteImage)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(compose); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
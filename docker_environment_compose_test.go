@@ -0,0 +1,89 @@
+package dockerit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+	"testing"
+	"time"
+)
+
+func TestNewComposeFile(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	a.Nil(context.setID("My.Env"))
+
+	_, err = context.addContainer(DockerComponent{
+		Name:  "it-redis",
+		Image: "redis",
+		ExposedPorts: []Port{
+			{ContainerPort: 6379, HostPort: 16379},
+		},
+	})
+	a.Nil(err)
+	_, err = context.addContainer(DockerComponent{
+		Name:  "it-app",
+		Image: "myapp",
+		ExposedPorts: []Port{
+			{ContainerPort: 8080, HostPort: 18080},
+		},
+		EnvironmentVariables: map[string]string{
+			"REDIS_PORT": `{{ value . "it-redis.Port"}}`,
+			"PASSWORD":   "pa$$word",
+		},
+		Cmd:         []string{"serve", "--port", "8080"},
+		Binds:       []string{"/tmp/config:/etc/config:ro", "app-data:/data"},
+		DNSServer:   "8.8.8.8",
+		Platform:    "linux/arm64",
+		Healthcheck: &Healthcheck{Test: []string{"CMD", "healthcheck"}, Interval: time.Second, Retries: 3},
+	})
+	a.Nil(err)
+	_, err = context.addContainer(DockerComponent{Name: "it-client", Image: "client"})
+	a.Nil(err)
+	a.Nil(context.configurePortBindings())
+	a.Nil(context.configureContainersEnv())
+
+	containers := []*dockerContainer{context.containers["it-redis"], context.containers["it-app"], context.containers["it-client"]}
+	compose, err := newComposeFile(context.ID, containers, func(image string) string { return "mirror/" + image })
+	a.Nil(err)
+
+	a.Equal(&composeFile{
+		Name: "myenv",
+		Services: map[string]composeService{
+			"it-redis": {
+				Image:       "mirror/redis",
+				Environment: map[string]string{},
+				Ports:       []string{"16379:6379"},
+			},
+			"it-app": {
+				Image:       "mirror/myapp",
+				Platform:    "linux/arm64",
+				Environment: map[string]string{"REDIS_PORT": "16379", "PASSWORD": "pa$$$$word"},
+				Ports:       []string{"18080:8080"},
+				Command:     []string{"serve", "--port", "8080"},
+				Volumes:     []string{"/tmp/config:/etc/config:ro", "app-data:/data"},
+				DNS:         []string{"8.8.8.8"},
+				Healthcheck: &composeHealthcheck{Test: []string{"CMD", "healthcheck"}, Interval: "1s", Retries: 3},
+				DependsOn:   map[string]composeDependency{"it-redis": {Condition: "service_started"}},
+			},
+			"it-client": {
+				Image:       "mirror/client",
+				Environment: map[string]string{},
+				DependsOn:   map[string]composeDependency{"it-app": {Condition: "service_healthy"}},
+			},
+		},
+		Volumes: map[string]composeVolume{"app-data": {Name: "app-data"}},
+	}, compose)
+
+	data, err := yaml.Marshal(compose)
+	a.Nil(err)
+	a.Contains(string(data), "name: myenv\nservices:\n")
+}
+
+func TestComposeProjectName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("myenv", ComposeProjectName("My.Env"))
+	a.Equal("it_env-1", ComposeProjectName("__-It_Env-1"))
+}
@@ -5,30 +5,119 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"net"
+	"os"
+	"regexp"
 	"strings"
+	"time"
 )
 
+const (
+	defaultPullParallelism = 4
+)
+
+// environment ID is a part of container names
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
 type dockerEnvironmentContext struct {
 	ID         string
 	logger     *logger
 	externalIP string
 	containers map[string]*dockerContainer
+	// normalized component names in the configured order
+	names []string
+	// maximal number of components started at once by StartParallel, unlimited if 0
+	parallelism int
+	// maximal number of images pulled at once
+	pullParallelism int
+	// optional rewrite of image names
+	imageRewriter ImageRewriter
+	// configuration of the docker clients
+	clientConfig ClientConfig
+	// optional docker network of the containers
+	network string
+	// additional container labels
+	labels map[string]string
+	// optional time to wait for a container to stop
+	stopTimeout *time.Duration
+	// optional container runtime used instead of the docker client
+	runtime Runtime
 }
 
 func newDockerEnvironmentContext() (*dockerEnvironmentContext, error) {
-	externalIP, err := externalIP()
-	if err != nil {
-		return nil, err
+	return newDockerEnvironmentContextWithOptions(environmentOptions{})
+}
+
+func newDockerEnvironmentContextWithOptions(options environmentOptions) (*dockerEnvironmentContext, error) {
+	ip := options.host
+	if ip == "" {
+		var err error
+		if ip, err = externalIP(); err != nil {
+			return nil, err
+		}
 	}
-	logger := newLogger()
-	logger.Info.Println("Using IP", externalIP)
+	logger := options.logger
+	if logger == nil {
+		logger = newLogger()
+	}
+	logger.Info.Println("Using IP", ip)
 	id := uuid.New().String()
 	id = id[len(id)-12:]
+	pullParallelism := defaultPullParallelism
+	if options.pullParallelism > 0 {
+		pullParallelism = options.pullParallelism
+	}
+
+	var imageRewriter ImageRewriter
+	if value := os.Getenv(registryMirrorEnv); value != "" {
+		mirrors, err := parseRegistryMirrors(value)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info.Println("Using registry mirrors", mirrors)
+		imageRewriter = NewRegistryMirrorRewriter(mirrors)
+	}
 
-	return &dockerEnvironmentContext{ID: id, logger: logger, externalIP: externalIP, containers: make(map[string]*dockerContainer)}, nil
+	context := &dockerEnvironmentContext{
+		ID:              id,
+		logger:          logger,
+		externalIP:      ip,
+		containers:      make(map[string]*dockerContainer),
+		parallelism:     options.parallelism,
+		pullParallelism: pullParallelism,
+		imageRewriter:   imageRewriter,
+		clientConfig:    options.clientConfig,
+		network:         options.network,
+		labels:          options.labels,
+		stopTimeout:     options.stopTimeout,
+		runtime:         options.runtime,
+	}
+	if options.id != "" {
+		if err := context.setID(options.id); err != nil {
+			return nil, err
+		}
+	}
+	return context, nil
+}
+
+// rewriteImage provides the image name used on the docker host
+func (r *dockerEnvironmentContext) rewriteImage(image string) string {
+	if r.imageRewriter == nil {
+		return image
+	}
+	return r.imageRewriter(image)
 }
 
-func normalizeName(name string) string {
+// setID sets the environment ID, which is a part of the container names
+func (r *dockerEnvironmentContext) setID(id string) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("Environment ID '%s' is invalid, expected %s", id, validID.String())
+	}
+	r.ID = id
+	return nil
+}
+
+// NormalizeName provides the normalized component name, component names are case insensitive
+func NormalizeName(name string) string {
 	return strings.ToLower(name)
 }
 
@@ -50,17 +139,29 @@ func (r *dockerEnvironmentContext) addContainer(component DockerComponent) (*doc
 	if component.Name == "" || component.Image == "" {
 		return nil, errors.New("DockerComponent Name and Image must not be empty")
 	}
-	name := normalizeName(component.Name)
+	switch component.PullPolicy {
+	case "", PullAlways, PullIfNotPresent, PullNever:
+	default:
+		return nil, fmt.Errorf("DockerComponent [%s] PullPolicy '%s' is invalid", component.Name, component.PullPolicy)
+	}
+	// templates are validated after resolution
+	if component.Platform != "" && !strings.Contains(component.Platform, "{{") {
+		if _, err := parsePlatform(component.Platform); err != nil {
+			return nil, err
+		}
+	}
+	name := NormalizeName(component.Name)
 	container := newDockerContainer(component)
 	if _, exits := r.containers[name]; exits {
 		return nil, fmt.Errorf("DockerComponent [%s] is configured twice", name)
 	}
 	r.containers[name] = container
+	r.names = append(r.names, name)
 	return container, nil
 }
 
 func (r *dockerEnvironmentContext) getContainer(name string) (*dockerContainer, error) {
-	container, exits := r.containers[normalizeName(name)]
+	container, exits := r.containers[NormalizeName(name)]
 	if !exits {
 		return nil, fmt.Errorf("DockerComponent [%s] is not configured", name)
 	}
@@ -166,3 +166,42 @@ func TestNewDockerEnvironmentEnvVariables(t *testing.T) {
 	a.Equal("mypassword", container.EnvironmentVariables["MYSQL_ROOT_PASSWORD"])
 
 }
+
+func TestNewDockerEnvironmentFailsOnInvalidPlatform(t *testing.T) {
+	a := assert.New(t)
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+
+	_, err = context.addContainer(DockerComponent{
+		Name:     "it-redis",
+		Image:    "redis",
+		Platform: "linux/arm/v7/extra",
+	})
+	a.EqualError(err, "Platform 'linux/arm/v7/extra' is invalid, expected os[/arch[/variant]]")
+}
+
+func TestNewDockerEnvironmentFailsOnInvalidPullPolicy(t *testing.T) {
+	a := assert.New(t)
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+
+	_, err = context.addContainer(DockerComponent{
+		Name:       "it-redis",
+		Image:      "redis",
+		PullPolicy: "Sometimes",
+	})
+	a.EqualError(err, "DockerComponent [it-redis] PullPolicy 'Sometimes' is invalid")
+}
+
+func TestDockerEnvironmentContextSetID(t *testing.T) {
+	a := assert.New(t)
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	a.Len(context.ID, 12)
+
+	a.Nil(context.setID("my-project_1"))
+	a.Equal("my-project_1", context.ID)
+
+	a.EqualError(context.setID("-project"), "Environment ID '-project' is invalid, expected ^[a-zA-Z0-9][a-zA-Z0-9_.-]*$")
+	a.Equal("my-project_1", context.ID)
+}
@@ -2,8 +2,10 @@ package dockerit
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 )
 
 type logWriter struct{ *log.Logger }
@@ -27,7 +29,79 @@ type logger struct {
 }
 
 func newLogger() *logger {
-	infoLogger := log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime)
-	errorLogger := log.New(os.Stdout, "ERROR: ", log.Ldate|log.Ltime)
+	return newWriterLogger(os.Stdout)
+}
+
+func newWriterLogger(w io.Writer) *logger {
+	infoLogger := log.New(w, "INFO: ", log.Ldate|log.Ltime)
+	errorLogger := log.New(w, "ERROR: ", log.Ldate|log.Ltime)
 	return &logger{Info: infoLogger, Error: errorLogger}
 }
+
+type stdoutLogConsumer struct{}
+
+// implements LogConsumer
+func (stdoutLogConsumer) Writers(componentName string) (io.Writer, io.Writer, error) {
+	out := stdoutWriter(componentName)
+	return out, out, nil
+}
+
+// hides io.Closer of the user provided writers
+type nonClosingWriter struct{ io.Writer }
+
+type writerLogConsumer struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// NewWriterLogConsumer creates a LogConsumer writing the container stdout and stderr to the given writers.
+// If stderr is nil, stdout is used for both. The writers are not closed when the log output ends.
+func NewWriterLogConsumer(stdout io.Writer, stderr io.Writer) LogConsumer {
+	if stderr == nil {
+		stderr = stdout
+	}
+	return &writerLogConsumer{stdout: nonClosingWriter{stdout}, stderr: nonClosingWriter{stderr}}
+}
+
+// implements LogConsumer
+func (r *writerLogConsumer) Writers(componentName string) (io.Writer, io.Writer, error) {
+	return r.stdout, r.stderr, nil
+}
+
+type fileLogConsumer struct {
+	dir string
+}
+
+// NewFileLogConsumer creates a LogConsumer appending the container stdout and stderr to the file <dir>/<component>.log
+func NewFileLogConsumer(dir string) LogConsumer {
+	return &fileLogConsumer{dir: dir}
+}
+
+// implements LogConsumer
+func (r *fileLogConsumer) Writers(componentName string) (io.Writer, io.Writer, error) {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	file, err := os.OpenFile(r.logFileName(componentName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, file, nil
+}
+
+func (r *fileLogConsumer) logFileName(componentName string) string {
+	return filepath.Join(r.dir, fmt.Sprintf("%s.log", componentName))
+}
+
+func closeLogWriters(stdout io.Writer, stderr io.Writer) error {
+	var err error
+	if closer, ok := stdout.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if closer, ok := stderr.(io.Closer); ok && stderr != stdout {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -1,10 +1,12 @@
 package dockerit
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -64,3 +66,56 @@ func TestLogger(t *testing.T) {
 	a.Contains(string(out), "ERROR: ")
 
 }
+
+func TestWriterLogConsumer(t *testing.T) {
+	a := assert.New(t)
+
+	var stdout, stderr bytes.Buffer
+	consumer := NewWriterLogConsumer(&stdout, &stderr)
+
+	dstout, dsterr, err := consumer.Writers("it-redis")
+	a.Nil(err)
+	io.WriteString(dstout, "out")
+	io.WriteString(dsterr, "err")
+	a.Nil(closeLogWriters(dstout, dsterr))
+
+	a.Equal("out", stdout.String())
+	a.Equal("err", stderr.String())
+}
+
+func TestWriterLogConsumerDoesNotCloseWriters(t *testing.T) {
+	a := assert.New(t)
+
+	r, w, err := os.Pipe()
+	a.Nil(err)
+	defer r.Close()
+
+	consumer := NewWriterLogConsumer(w, nil)
+	dstout, dsterr, err := consumer.Writers("it-redis")
+	a.Nil(err)
+	a.Nil(closeLogWriters(dstout, dsterr))
+
+	_, err = io.WriteString(w, "still open")
+	a.Nil(err)
+	w.Close()
+}
+
+func TestFileLogConsumer(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "docker-it-logs")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+
+	consumer := NewFileLogConsumer(filepath.Join(dir, "logs"))
+	for _, line := range []string{"first\n", "second\n"} {
+		dstout, dsterr, err := consumer.Writers("it-redis")
+		a.Nil(err)
+		io.WriteString(dstout, line)
+		a.Nil(closeLogWriters(dstout, dsterr))
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "logs", "it-redis.log"))
+	a.Nil(err)
+	a.Equal("first\nsecond\n", string(out))
+}
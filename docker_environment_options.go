@@ -0,0 +1,139 @@
+package dockerit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+)
+
+// Option configures a docker environment, see NewDockerEnvironmentWithOptions
+type Option func(*environmentOptions) error
+
+type environmentOptions struct {
+	id              string
+	host            string
+	logger          *logger
+	clientConfig    ClientConfig
+	network         string
+	labels          map[string]string
+	stopTimeout     *time.Duration
+	parallelism     int
+	pullParallelism int
+	runtime         Runtime
+}
+
+// WithID sets the environment ID, which is appended to the container names. If empty, a random one is used.
+func WithID(id string) Option {
+	return func(options *environmentOptions) error {
+		options.id = id
+		return nil
+	}
+}
+
+// WithHost sets the IP the container ports are bound to and provided by Host. If not set, the IP of the first
+// network interface is used.
+func WithHost(ip string) Option {
+	return func(options *environmentOptions) error {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("Host '%s' is not an IP address", ip)
+		}
+		options.host = ip
+		return nil
+	}
+}
+
+// WithLogger sets the logger of the library log output. If not set, the output is written to stdout.
+func WithLogger(l *log.Logger) Option {
+	return func(options *environmentOptions) error {
+		if l == nil {
+			return errors.New("Logger must not be nil")
+		}
+		options.logger = &logger{
+			Info:  log.New(l.Writer(), l.Prefix()+"INFO: ", l.Flags()),
+			Error: log.New(l.Writer(), l.Prefix()+"ERROR: ", l.Flags()),
+		}
+		return nil
+	}
+}
+
+// WithClient sets the docker client configuration
+func WithClient(config ClientConfig) Option {
+	return func(options *environmentOptions) error {
+		options.clientConfig = config
+		return nil
+	}
+}
+
+// WithNetwork connects the containers to the docker network, where the containers are reachable by their component names.
+// If the network does not exist, it is created when the first container is created and removed by Shutdown.
+func WithNetwork(name string) Option {
+	return func(options *environmentOptions) error {
+		if name == "" {
+			return errors.New("Network name must not be empty")
+		}
+		options.network = name
+		return nil
+	}
+}
+
+// WithLabels adds labels to the containers and the created network
+func WithLabels(labels map[string]string) Option {
+	return func(options *environmentOptions) error {
+		for k := range labels {
+			if k == LabelEnvironmentID || k == LabelComponent {
+				return fmt.Errorf("Label '%s' is reserved", k)
+			}
+		}
+		options.labels = labels
+		return nil
+	}
+}
+
+// WithStopTimeout sets the time to wait for a container to stop before it is killed. If not set, the docker default is used.
+func WithStopTimeout(timeout time.Duration) Option {
+	return func(options *environmentOptions) error {
+		if timeout < 0 {
+			return fmt.Errorf("Stop timeout %s must not be negative", timeout)
+		}
+		options.stopTimeout = &timeout
+		return nil
+	}
+}
+
+// WithParallelism sets the maximal number of components started at once by StartParallel, all at once by default.
+// It does not limit the image pulls, see WithPullParallelism.
+func WithParallelism(parallelism int) Option {
+	return func(options *environmentOptions) error {
+		if parallelism < 1 {
+			return fmt.Errorf("Parallelism %d must be positive", parallelism)
+		}
+		options.parallelism = parallelism
+		return nil
+	}
+}
+
+// WithPullParallelism sets the maximal number of images pulled at once by PullImages and SaveImages, 4 by default.
+// It does not limit StartParallel, see WithParallelism.
+func WithPullParallelism(parallelism int) Option {
+	return func(options *environmentOptions) error {
+		if parallelism < 1 {
+			return fmt.Errorf("Pull parallelism %d must be positive", parallelism)
+		}
+		options.pullParallelism = parallelism
+		return nil
+	}
+}
+
+// WithRuntime sets the container runtime used instead of the docker client, e.g. the in-memory runtime of the dockerittest package.
+// The runtime is not closed by the environment.
+func WithRuntime(runtime Runtime) Option {
+	return func(options *environmentOptions) error {
+		if runtime == nil {
+			return errors.New("Runtime must not be nil")
+		}
+		options.runtime = runtime
+		return nil
+	}
+}
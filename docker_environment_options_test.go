@@ -0,0 +1,72 @@
+package dockerit
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"testing"
+	"time"
+)
+
+func newTestOptions(t *testing.T, opts ...Option) environmentOptions {
+	var options environmentOptions
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return options
+}
+
+func TestNewDockerEnvironmentContextWithOptions(t *testing.T) {
+	a := assert.New(t)
+
+	var output bytes.Buffer
+	options := newTestOptions(t,
+		WithID("it-42"),
+		WithHost("10.0.0.1"),
+		WithLogger(log.New(&output, "test ", 0)),
+		WithNetwork("it-net"),
+		WithLabels(map[string]string{"team": "payments"}),
+		WithStopTimeout(5*time.Second),
+		WithParallelism(3),
+		WithPullParallelism(2),
+	)
+	context, err := newDockerEnvironmentContextWithOptions(options)
+	a.Nil(err)
+	a.Equal("it-42", context.ID)
+	a.Equal("10.0.0.1", context.Host())
+	a.Equal("it-net", context.network)
+	a.Equal(3, context.parallelism)
+	a.Equal(2, context.pullParallelism)
+	a.Equal(5*time.Second, *context.stopTimeout)
+	a.Equal("test INFO: Using IP 10.0.0.1\n", output.String())
+
+	handler := &dockerLifecycleHandler{context: context}
+	a.Equal(map[string]string{"team": "payments", LabelEnvironmentID: "it-42", LabelComponent: "it-redis"}, handler.getContainerLabels("it-redis"))
+}
+
+func TestNewDockerEnvironmentContextWithInvalidID(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := newDockerEnvironmentContextWithOptions(newTestOptions(t, WithID("-it")))
+	a.NotNil(err)
+	a.Contains(err.Error(), "Environment ID '-it' is invalid")
+}
+
+func TestInvalidOptions(t *testing.T) {
+	a := assert.New(t)
+
+	var options environmentOptions
+	a.EqualError(WithHost("localhost")(&options), "Host 'localhost' is not an IP address")
+	a.EqualError(WithLogger(nil)(&options), "Logger must not be nil")
+	a.EqualError(WithNetwork("")(&options), "Network name must not be empty")
+	a.EqualError(WithLabels(map[string]string{LabelComponent: "x"})(&options), "Label 'dockerit.component' is reserved")
+	a.EqualError(WithStopTimeout(-time.Second)(&options), "Stop timeout -1s must not be negative")
+	a.EqualError(WithParallelism(0)(&options), "Parallelism 0 must be positive")
+	a.EqualError(WithPullParallelism(0)(&options), "Pull parallelism 0 must be positive")
+	a.EqualError(WithRuntime(nil)(&options), "Runtime must not be nil")
+
+	_, err := NewDockerEnvironmentWithOptions([]Option{WithPullParallelism(0)}, DockerComponent{Name: "it-redis", Image: "redis"})
+	a.EqualError(err, "Pull parallelism 0 must be positive")
+}
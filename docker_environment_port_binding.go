@@ -54,7 +54,7 @@ func (r *dockerEnvironmentPortBinding) getNormalizedExposedPorts() (map[string][
 						containerName, exposedPort.ContainerPort)
 				}
 
-				portName := normalizeName(exposedPort.Name)
+				portName := NormalizeName(exposedPort.Name)
 				if portName == "" {
 					portName = containerName
 				}
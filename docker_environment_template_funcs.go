@@ -0,0 +1,102 @@
+package dockerit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs provides the functions available in templates. The functions are a stable API:
+//
+//	value . "key"                      value of the key, e.g. value . "it-redis.Port"
+//	hostport "component" ["port"]      host:port of the default or the named port
+//	url "scheme" "component" ["port"]  scheme://host:port of the default or the named port
+//	brokers "component"...             comma-separated host:port of the default ports of the components
+//	env "NAME" ["default"]             environment variable or the default if it is not set or empty
+//	join "sep" values...               values joined by the separator, lists are flattened
+//	upper "s", lower "s"               upper or lower case
+//	b64enc "s"                         base64 encoding
+//	quote "s"                          double-quoted Go string literal
+//	json value                         JSON encoding
+//
+// Unknown components and ports fail the template execution as unknown keys do.
+func (r *dockerEnvironmentValueResolver) templateFuncs(variables map[string]interface{}) template.FuncMap {
+	hostport := func(component string, portName ...string) (string, error) {
+		key := component
+		if len(portName) > 1 {
+			return "", fmt.Errorf("hostport accepts a component and an optional port name, got %d port names", len(portName))
+		} else if len(portName) == 1 && portName[0] != "" {
+			key += "." + portName[0]
+		}
+		host, err := r.value(variables, component+"."+qualifierHost)
+		if err != nil {
+			return "", err
+		}
+		port, err := r.value(variables, key+"."+qualifierPort)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%v:%v", host, port), nil
+	}
+	return template.FuncMap{
+		"value":    r.value,
+		"hostport": hostport,
+		"url": func(scheme string, component string, portName ...string) (string, error) {
+			address, err := hostport(component, portName...)
+			if err != nil {
+				return "", err
+			}
+			return scheme + "://" + address, nil
+		},
+		"brokers": func(components ...string) (string, error) {
+			addresses := make([]string, 0, len(components))
+			for _, component := range components {
+				address, err := hostport(component)
+				if err != nil {
+					return "", err
+				}
+				addresses = append(addresses, address)
+			}
+			return strings.Join(addresses, ","), nil
+		},
+		"env": func(name string, defaultValue ...string) string {
+			if value := os.Getenv(name); value != "" || len(defaultValue) == 0 {
+				return value
+			}
+			return defaultValue[0]
+		},
+		"join": func(sep string, values ...interface{}) string {
+			result := make([]string, 0, len(values))
+			for _, value := range values {
+				switch v := value.(type) {
+				case []string:
+					result = append(result, v...)
+				case []interface{}:
+					for _, item := range v {
+						result = append(result, fmt.Sprint(item))
+					}
+				default:
+					result = append(result, fmt.Sprint(v))
+				}
+			}
+			return strings.Join(result, sep)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"b64enc": func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		},
+		"quote": strconv.Quote,
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			return string(data), nil
+		},
+	}
+}
@@ -0,0 +1,74 @@
+package dockerit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func newTemplateFuncsTestResolver(a *assert.Assertions) *dockerEnvironmentValueResolver {
+	environmentContext, err := newDockerEnvironmentContext()
+	a.Nil(err)
+
+	kafka1, err := environmentContext.addContainer(DockerComponent{Name: "it-kafka1", Image: "kafka"})
+	a.Nil(err)
+	kafka1.portBindings = []Port{
+		{ContainerPort: 9092, HostPort: 32401},
+		{Name: "zookeeper", ContainerPort: 2181, HostPort: 32402},
+	}
+	kafka2, err := environmentContext.addContainer(DockerComponent{Name: "it-kafka2", Image: "kafka"})
+	a.Nil(err)
+	kafka2.portBindings = []Port{
+		{ContainerPort: 9092, HostPort: 32403},
+	}
+	return &dockerEnvironmentValueResolver{ip: "192.168.178.44", context: environmentContext}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	a := assert.New(t)
+	resolver := newTemplateFuncsTestResolver(a)
+
+	os.Setenv("DOCKER_IT_TEMPLATE_TEST", "value")
+	defer os.Unsetenv("DOCKER_IT_TEMPLATE_TEST")
+
+	tests := map[string]string{
+		`{{ hostport "it-kafka1" }}`:                             "192.168.178.44:32401",
+		`{{ hostport "it-kafka1" "zookeeper" }}`:                 "192.168.178.44:32402",
+		`{{ url "http" "it-kafka2" }}`:                           "http://192.168.178.44:32403",
+		`{{ url "tcp" "it-kafka1" "zookeeper" }}`:                "tcp://192.168.178.44:32402",
+		`{{ brokers "it-kafka1" "it-kafka2" }}`:                  "192.168.178.44:32401,192.168.178.44:32403",
+		`{{ env "DOCKER_IT_TEMPLATE_TEST" "default" }}`:          "value",
+		`{{ env "DOCKER_IT_TEMPLATE_UNDEFINED" "default" }}`:     "default",
+		`{{ env "DOCKER_IT_TEMPLATE_UNDEFINED" }}`:               "",
+		`{{ join "," "a" "b" }}`:                                 "a,b",
+		`{{ upper "it-kafka1" }}`:                                "IT-KAFKA1",
+		`{{ lower "IT-KAFKA1" }}`:                                "it-kafka1",
+		`{{ b64enc "user:password" }}`:                           "dXNlcjpwYXNzd29yZA==",
+		`{{ quote "a \"b\"" }}`:                                  `"a \"b\""`,
+		`{{ json (hostport "it-kafka2") }}`:                      `"192.168.178.44:32403"`,
+		`jdbc:postgresql://{{ hostport "it-kafka1" }}/db`:        "jdbc:postgresql://192.168.178.44:32401/db",
+		`{{ value . "it-kafka1.zookeeper.Port" | printf "%s" }}`: "32402",
+	}
+	for template, expected := range tests {
+		value, err := resolver.resolve(template)
+		a.Nil(err, template)
+		a.Equal(expected, value, template)
+	}
+}
+
+func TestTemplateFuncsUnknownComponent(t *testing.T) {
+	a := assert.New(t)
+	resolver := newTemplateFuncsTestResolver(a)
+
+	_, err := resolver.resolve(`{{ hostport "it-redis" }}`)
+	a.NotNil(err)
+	a.Contains(err.Error(), "Unknown key 'it-redis.Host'")
+
+	_, err = resolver.resolve(`{{ brokers "it-kafka1" "it-kafka3" }}`)
+	a.NotNil(err)
+	a.Contains(err.Error(), "Unknown key 'it-kafka3.Host'")
+
+	_, err = resolver.resolve(`{{ url "http" "it-kafka1" "admin" }}`)
+	a.NotNil(err)
+	a.Contains(err.Error(), "Unknown key 'it-kafka1.admin.Port'")
+}
@@ -16,6 +16,7 @@ func TestNewDockerEnvironmentFailsWhenComponentListIsEmpty(t *testing.T) {
 }
 
 func TestNewDockerEnvironmentStartFails(t *testing.T) {
+	skipWithoutDaemon(t)
 	a := assert.New(t)
 
 	env, err := NewDockerEnvironment(
@@ -35,6 +36,7 @@ func TestNewDockerEnvironmentStartFails(t *testing.T) {
 }
 
 func TestNewDockerEnvironmentLifeCycle(t *testing.T) {
+	skipWithoutDaemon(t)
 	a := assert.New(t)
 
 	env, err := NewDockerEnvironment(
@@ -76,6 +78,7 @@ func TestNewDockerEnvironmentLifeCycle(t *testing.T) {
 }
 
 func TestNewDockerEnvironmentWithShutdown(t *testing.T) {
+	skipWithoutDaemon(t)
 	a := assert.New(t)
 
 	env, err := NewDockerEnvironment(
@@ -102,3 +105,30 @@ func TestNewDockerEnvironmentWithShutdown(t *testing.T) {
 	}
 	a.Equal(uint32(1), atomic.LoadUint32(&counter))
 }
+
+func TestNewDockerEnvironmentPullImages(t *testing.T) {
+	skipWithoutDaemon(t)
+	a := assert.New(t)
+
+	env, err := NewDockerEnvironment(
+		DockerComponent{
+			Name:       "it-busybox",
+			Image:      "busybox",
+			PullPolicy: PullAlways,
+		},
+		DockerComponent{
+			Name:       "it-busybox2",
+			Image:      "busybox",
+			PullPolicy: PullIfNotPresent,
+		},
+	)
+	a.Nil(err)
+	defer env.Shutdown()
+
+	err = env.PullImages()
+	a.Nil(err)
+	a.Len(env.lifecycleHandler.imageTasks, 1)
+
+	err = env.StartParallel("it-busybox", "it-busybox2")
+	a.Nil(err)
+}
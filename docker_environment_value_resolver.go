@@ -16,6 +16,9 @@ const (
 	qualifierTargetPort    = "TargetPort"    // exposed port within container
 	qualifierHostPort      = "HostPort"      // mapped port on host
 	qualifierPort          = "Port"          // mapped port on host
+	qualifierContainerID   = "ContainerID"   // container ID, known after the container is created
+	qualifierContainerName = "ContainerName" // container name, known after the container is created
+	qualifierIP            = "IP"            // container IP, known after the container is started
 )
 
 type dockerEnvironmentValueResolver struct {
@@ -44,21 +47,126 @@ func (r *dockerEnvironmentValueResolver) configureContainersEnv() error {
 	if err != nil {
 		return err
 	}
+	// runtime values are resolved again when the container is created
+	r.appendRuntimePlaceholders(contextVariables)
 	for containerName, container := range r.context.containers {
-		if container.EnvironmentVariables == nil {
-			continue
+		if err := r.configureContainer(containerName, container, contextVariables); err != nil {
+			return err
 		}
-		env := make(map[string]string)
-		for k, v := range container.EnvironmentVariables {
-			value, err := r.resolveValue(fmt.Sprintf("DockerComponent %s Env %s", containerName, k), v, contextVariables)
+	}
+	return nil
+}
+
+// configureContainerRuntime resolves the templates of the container with the runtime values of the components
+// created or started before
+func (r *dockerEnvironmentValueResolver) configureContainerRuntime(container *dockerContainer) error {
+	contextVariables, err := r.getEnvironmentContextVariables()
+	if err != nil {
+		return err
+	}
+	return r.configureContainer(NormalizeName(container.Name), container, contextVariables)
+}
+
+func (r *dockerEnvironmentValueResolver) configureContainer(containerName string, container *dockerContainer, contextVariables map[string]interface{}) error {
+	if err := r.resolveContainer(containerName, container, contextVariables); err != nil {
+		return err
+	}
+	if container.EnvironmentVariables == nil {
+		return nil
+	}
+	env := make(map[string]string)
+	for k, v := range container.EnvironmentVariables {
+		value, err := r.resolveValue(fmt.Sprintf("DockerComponent %s Env %s", containerName, k), v, contextVariables)
+		if err != nil {
+			return err
+		}
+		env[k] = value
+	}
+	// assign env to container
+	container.env = env
+	return nil
+}
+
+// appendRuntimePlaceholders adds empty values for the runtime values, which are not known yet
+func (r *dockerEnvironmentValueResolver) appendRuntimePlaceholders(result map[string]interface{}) {
+	for containerName, container := range r.context.containers {
+		for _, name := range []string{container.DockerComponent.Name, containerName} {
+			for _, qualifier := range []string{qualifierContainerID, qualifierContainerName, qualifierIP} {
+				key := fmt.Sprintf("%s.%s", name, qualifier)
+				if _, exists := result[key]; !exists {
+					result[key] = ""
+				}
+			}
+		}
+	}
+}
+
+// resolveContainer resolves the templates of the component string fields
+func (r *dockerEnvironmentValueResolver) resolveContainer(containerName string, container *dockerContainer, contextVariables map[string]interface{}) error {
+	resolveField := func(field string, value string) (string, error) {
+		return r.resolveValue(fmt.Sprintf("DockerComponent %s %s", containerName, field), value, contextVariables)
+	}
+	resolveList := func(field string, values []string) ([]string, error) {
+		if values == nil {
+			return nil, nil
+		}
+		result := make([]string, len(values))
+		for i, value := range values {
+			resolved, err := resolveField(fmt.Sprintf("%s[%d]", field, i), value)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			env[k] = value
+			result[i] = resolved
+		}
+		return result, nil
+	}
+
+	var resolved resolvedComponent
+	var err error
+	if resolved.image, err = resolveField("Image", container.Image); err != nil {
+		return err
+	}
+	if resolved.platform, err = resolveField("Platform", container.Platform); err != nil {
+		return err
+	}
+	if _, err := parsePlatform(resolved.platform); err != nil {
+		return err
+	}
+	if resolved.imageArchive, err = resolveField("ImageArchive", container.ImageArchive); err != nil {
+		return err
+	}
+	if auth := container.RegistryAuth; auth != nil {
+		resolved.registryAuth = &RegistryAuth{}
+		if resolved.registryAuth.Username, err = resolveField("RegistryAuth.Username", auth.Username); err != nil {
+			return err
+		}
+		if resolved.registryAuth.Password, err = resolveField("RegistryAuth.Password", auth.Password); err != nil {
+			return err
 		}
-		// assign env to container
-		container.env = env
+		if resolved.registryAuth.IdentityToken, err = resolveField("RegistryAuth.IdentityToken", auth.IdentityToken); err != nil {
+			return err
+		}
+	}
+	if resolved.cmd, err = resolveList("Cmd", container.Cmd); err != nil {
+		return err
 	}
+	if resolved.entrypoint, err = resolveList("Entrypoint", container.Entrypoint); err != nil {
+		return err
+	}
+	if resolved.binds, err = resolveList("Binds", container.Binds); err != nil {
+		return err
+	}
+	if resolved.dnsServer, err = resolveField("DNSServer", container.DNSServer); err != nil {
+		return err
+	}
+	if healthcheck := container.Healthcheck; healthcheck != nil {
+		resolvedHealthcheck := *healthcheck
+		if resolvedHealthcheck.Test, err = resolveList("Healthcheck.Test", healthcheck.Test); err != nil {
+			return err
+		}
+		resolved.healthcheck = &resolvedHealthcheck
+	}
+	container.resolved = resolved
 	return nil
 }
 
@@ -79,11 +187,7 @@ func (r *dockerEnvironmentValueResolver) resolve(templateText string) (string, e
 
 func (r *dockerEnvironmentValueResolver) resolveValue(templateName string, templateText string, contextVariables map[string]interface{}) (string, error) {
 
-	var funcMap = template.FuncMap{
-		"value": r.value,
-	}
-
-	t := template.New(templateName).Funcs(funcMap).Option("missingkey=error")
+	t := template.New(templateName).Funcs(r.templateFuncs(contextVariables)).Option("missingkey=error")
 	t, err := t.Parse(templateText)
 	if err != nil {
 		return "", err
@@ -113,7 +217,7 @@ func (r *dockerEnvironmentValueResolver) appendContainerContextVariables(name st
 	result[fmt.Sprintf("%s.%s", name, qualifierHost)] = ip
 
 	for _, port := range container.portBindings {
-		if port.Name == "" || normalizeName(port.Name) == normalizeName(name) {
+		if port.Name == "" || NormalizeName(port.Name) == NormalizeName(name) {
 			result[fmt.Sprintf("%s.%s", name, qualifierPort)] = strconv.Itoa(port.HostPort)
 			result[fmt.Sprintf("%s.%s", name, qualifierHostPort)] = strconv.Itoa(port.HostPort)
 			result[fmt.Sprintf("%s.%s", name, qualifierContainerPort)] = strconv.Itoa(port.ContainerPort)
@@ -130,12 +234,22 @@ func (r *dockerEnvironmentValueResolver) appendContainerContextVariables(name st
 	// original names (no lowercase)
 	for _, exposedPorts := range container.DockerComponent.ExposedPorts {
 		if exposedPorts.Name != "" {
-			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierPort)] = result[fmt.Sprintf("%s.%s.%s", name, normalizeName(exposedPorts.Name), qualifierPort)]
-			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierHostPort)] = result[fmt.Sprintf("%s.%s.%s", name, normalizeName(exposedPorts.Name), qualifierHostPort)]
-			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierContainerPort)] = result[fmt.Sprintf("%s.%s.%s", name, normalizeName(exposedPorts.Name), qualifierContainerPort)]
-			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierTargetPort)] = result[fmt.Sprintf("%s.%s.%s", name, normalizeName(exposedPorts.Name), qualifierTargetPort)]
+			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierPort)] = result[fmt.Sprintf("%s.%s.%s", name, NormalizeName(exposedPorts.Name), qualifierPort)]
+			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierHostPort)] = result[fmt.Sprintf("%s.%s.%s", name, NormalizeName(exposedPorts.Name), qualifierHostPort)]
+			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierContainerPort)] = result[fmt.Sprintf("%s.%s.%s", name, NormalizeName(exposedPorts.Name), qualifierContainerPort)]
+			result[fmt.Sprintf("%s.%s.%s", name, exposedPorts.Name, qualifierTargetPort)] = result[fmt.Sprintf("%s.%s.%s", name, NormalizeName(exposedPorts.Name), qualifierTargetPort)]
 		}
 	}
+
+	// runtime values
+	containerID, containerName, ip := container.runtimeValues()
+	if containerID != "" {
+		result[fmt.Sprintf("%s.%s", name, qualifierContainerID)] = containerID
+		result[fmt.Sprintf("%s.%s", name, qualifierContainerName)] = containerName
+	}
+	if ip != "" {
+		result[fmt.Sprintf("%s.%s", name, qualifierIP)] = ip
+	}
 }
 
 func (r *dockerEnvironmentValueResolver) getSystemContextVariables() map[string]interface{} {
@@ -154,9 +268,9 @@ func (r *dockerEnvironmentValueResolver) port(componentName string, portName str
 
 	var tpl string
 	if portName == "" {
-		tpl = fmt.Sprintf(`{{ value . "%s.%s"}}`, normalizeName(componentName), qualifierPort)
+		tpl = fmt.Sprintf(`{{ value . "%s.%s"}}`, NormalizeName(componentName), qualifierPort)
 	} else {
-		tpl = fmt.Sprintf(`{{ value . "%s.%s.%s"}}`, normalizeName(componentName), normalizeName(portName), qualifierPort)
+		tpl = fmt.Sprintf(`{{ value . "%s.%s.%s"}}`, NormalizeName(componentName), NormalizeName(portName), qualifierPort)
 	}
 
 	val, err := r.resolve(tpl)
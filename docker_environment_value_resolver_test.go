@@ -341,3 +341,101 @@ func TestRequireContainerPortBindings(t *testing.T) {
 	a.True(err != nil)
 	a.Equal(`portBindings for 'redis' is not defined`, err.Error())
 }
+
+func TestConfigureContainersResolvesComponentFields(t *testing.T) {
+	a := assert.New(t)
+
+	environmentContext, err := newDockerEnvironmentContext()
+	a.Nil(err)
+
+	os.Setenv("DOCKER_IT_APP_VERSION", "1.2.3")
+	defer os.Unsetenv("DOCKER_IT_APP_VERSION")
+
+	_, err = environmentContext.addContainer(DockerComponent{
+		Name:         "it-redis",
+		Image:        "redis",
+		ExposedPorts: []Port{{ContainerPort: 6379, HostPort: 32401}},
+	})
+	a.Nil(err)
+	app, err := environmentContext.addContainer(DockerComponent{
+		Name:         "it-app",
+		Image:        `myapp:{{ env "DOCKER_IT_APP_VERSION" }}`,
+		RegistryAuth: &RegistryAuth{Username: "ci", Password: `{{ env "DOCKER_IT_APP_VERSION" }}`},
+		Cmd:          []string{"serve", `--redis={{ hostport "it-redis" }}`},
+		Entrypoint:   []string{"/bin/app", `--port={{ value . "it-redis.ContainerPort" }}`},
+		Binds:        []string{`/tmp/{{ value . "it-redis.Port" }}:/data`},
+		Healthcheck:  &Healthcheck{Test: []string{"CMD", "check", `{{ value . "it-redis.Port" }}`}},
+	})
+	a.Nil(err)
+	resolver := &dockerEnvironmentValueResolver{ip: "192.168.178.44", context: environmentContext}
+	a.Nil(newDockerEnvironmentPortBinding("0.0.0.0", environmentContext).configurePortBindings())
+	a.Nil(resolver.configureContainersEnv())
+
+	a.Equal("myapp:1.2.3", app.resolved.image)
+	a.Equal(&RegistryAuth{Username: "ci", Password: "1.2.3"}, app.resolved.registryAuth)
+	a.Equal([]string{"serve", "--redis=192.168.178.44:32401"}, app.resolved.cmd)
+	a.Equal([]string{"/bin/app", "--port=6379"}, app.resolved.entrypoint)
+	a.Equal([]string{"/tmp/32401:/data"}, app.resolved.binds)
+	a.Equal([]string{"CMD", "check", "32401"}, app.resolved.healthcheck.Test)
+	// the component keeps the templates
+	a.Equal(`myapp:{{ env "DOCKER_IT_APP_VERSION" }}`, app.Image)
+}
+
+func TestConfigureContainersReportsFieldTemplateErrors(t *testing.T) {
+	a := assert.New(t)
+
+	environmentContext, err := newDockerEnvironmentContext()
+	a.Nil(err)
+
+	container, err := environmentContext.addContainer(DockerComponent{
+		Name:  "it-app",
+		Image: "myapp",
+		Cmd:   []string{"serve", `--redis={{ value . "it-redis.Port" }}`},
+	})
+	a.Nil(err)
+	container.portBindings = make([]Port, 0)
+	resolver := &dockerEnvironmentValueResolver{ip: "192.168.178.44", context: environmentContext}
+
+	err = resolver.configureContainersEnv()
+	a.NotNil(err)
+	a.Contains(err.Error(), "DockerComponent it-app Cmd[1]")
+	a.Contains(err.Error(), "Unknown key 'it-redis.Port'")
+}
+
+func TestConfigureContainerRuntimeValues(t *testing.T) {
+	a := assert.New(t)
+
+	environmentContext, err := newDockerEnvironmentContext()
+	a.Nil(err)
+
+	db, err := environmentContext.addContainer(DockerComponent{
+		Name:         "it-db",
+		Image:        "postgres",
+		ExposedPorts: []Port{{ContainerPort: 5432, HostPort: 32402}},
+	})
+	a.Nil(err)
+	app, err := environmentContext.addContainer(DockerComponent{
+		Name:                 "it-app",
+		Image:                "myapp",
+		Cmd:                  []string{`--db={{ value . "it-db.IP" }}:{{ value . "it-db.ContainerPort" }}`},
+		EnvironmentVariables: map[string]string{"DB_CONTAINER": `{{ value . "it-db.ContainerName" }}/{{ value . "it-db.ContainerID" }}`},
+	})
+	a.Nil(err)
+	resolver := &dockerEnvironmentValueResolver{ip: "192.168.178.44", context: environmentContext}
+	a.Nil(newDockerEnvironmentPortBinding("0.0.0.0", environmentContext).configurePortBindings())
+
+	// runtime values are empty until the dependency is created
+	a.Nil(resolver.configureContainersEnv())
+	a.Equal([]string{"--db=:5432"}, app.resolved.cmd)
+
+	err = resolver.configureContainerRuntime(app)
+	a.NotNil(err)
+	a.Contains(err.Error(), "DockerComponent it-app Cmd[0]")
+
+	db.containerID = "4a5f0e1c2b3d"
+	db.containerName = "it-db-" + environmentContext.ID
+	db.ip = "172.17.0.2"
+	a.Nil(resolver.configureContainerRuntime(app))
+	a.Equal([]string{"--db=172.17.0.2:5432"}, app.resolved.cmd)
+	a.Equal("it-db-"+environmentContext.ID+"/4a5f0e1c2b3d", app.env["DB_CONTAINER"])
+}
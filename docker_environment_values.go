@@ -0,0 +1,96 @@
+package dockerit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// KeyMapper maps the keys of the environment values, e.g. EnvVarKeyMapper
+type KeyMapper func(key string) string
+
+var invalidEnvVarChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// EnvVarKeyMapper maps a key to an environment variable name, e.g. it-redis.Port to IT_REDIS_PORT
+func EnvVarKeyMapper(key string) string {
+	return invalidEnvVarChars.ReplaceAllString(strings.ToUpper(key), "_")
+}
+
+// Values provides all values available in templates, e.g. it-redis.Host, it-redis.Port or it-kafka.zookeeper.Port
+func (r *DockerEnvironment) Values() map[string]string {
+	variables, err := r.context.getValueResolver().getEnvironmentContextVariables()
+	if err != nil {
+		// the port bindings are configured by the environment creation
+		r.context.logger.Error.Println("Environment values are not available:", err)
+		return make(map[string]string)
+	}
+	values := make(map[string]string, len(variables))
+	for k, v := range variables {
+		values[k] = fmt.Sprint(v)
+	}
+	return values
+}
+
+// mapValues applies the key mapper. Keys mapped to the same key must have the same value.
+func mapValues(values map[string]string, mapper KeyMapper) (map[string]string, error) {
+	if mapper == nil {
+		return values, nil
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	mapped := make(map[string]string, len(values))
+	origins := make(map[string]string, len(values))
+	for _, k := range keys {
+		mappedKey := mapper(k)
+		if value, exists := mapped[mappedKey]; exists && value != values[k] {
+			return nil, fmt.Errorf("Keys '%s' and '%s' are both mapped to '%s'", origins[mappedKey], k, mappedKey)
+		}
+		mapped[mappedKey] = values[k]
+		origins[mappedKey] = k
+	}
+	return mapped, nil
+}
+
+// WriteEnvFile writes the environment values as KEY=value lines, e.g. for docker --env-file or a subprocess.
+// If the mapper is nil, EnvVarKeyMapper is used.
+func (r *DockerEnvironment) WriteEnvFile(path string, mapper KeyMapper) error {
+	if mapper == nil {
+		mapper = EnvVarKeyMapper
+	}
+	values, err := mapValues(r.Values(), mapper)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, values[k])
+	}
+	return ioutil.WriteFile(path, b.Bytes(), 0644)
+}
+
+// WriteJSON writes the environment values as a JSON object. If the mapper is nil, the keys are not mapped.
+func (r *DockerEnvironment) WriteJSON(path string, mapper KeyMapper) error {
+	values, err := mapValues(r.Values(), mapper)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
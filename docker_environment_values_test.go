@@ -0,0 +1,84 @@
+package dockerit
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newValuesTestEnvironment(a *assert.Assertions) *DockerEnvironment {
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	_, err = context.addContainer(DockerComponent{
+		Name:  "It-Redis",
+		Image: "redis",
+		ExposedPorts: []Port{
+			{ContainerPort: 6379, HostPort: 16379},
+			{Name: "sentinel", ContainerPort: 26379, HostPort: 36379},
+		},
+	})
+	a.Nil(err)
+	a.Nil(context.configurePortBindings())
+	context.externalIP = "10.0.0.1"
+	return &DockerEnvironment{context: context}
+}
+
+func TestDockerEnvironmentValues(t *testing.T) {
+	a := assert.New(t)
+	env := newValuesTestEnvironment(a)
+
+	values := env.Values()
+	a.Equal("10.0.0.1", values["it-redis.Host"])
+	a.Equal("10.0.0.1", values["It-Redis.Host"])
+	a.Equal("16379", values["it-redis.Port"])
+	a.Equal("6379", values["it-redis.ContainerPort"])
+	a.Equal("36379", values["it-redis.sentinel.Port"])
+}
+
+func TestEnvVarKeyMapper(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("IT_REDIS_PORT", EnvVarKeyMapper("it-redis.Port"))
+	a.Equal("IT_KAFKA_ZOOKEEPER_HOSTPORT", EnvVarKeyMapper("it-kafka.zookeeper.HostPort"))
+}
+
+func TestMapValues(t *testing.T) {
+	a := assert.New(t)
+
+	values, err := mapValues(map[string]string{"it-redis.Port": "1", "It-Redis.Port": "1"}, EnvVarKeyMapper)
+	a.Nil(err)
+	a.Equal(map[string]string{"IT_REDIS_PORT": "1"}, values)
+
+	_, err = mapValues(map[string]string{"it-redis.Port": "1", "it_redis.Port": "2"}, EnvVarKeyMapper)
+	a.EqualError(err, "Keys 'it-redis.Port' and 'it_redis.Port' are both mapped to 'IT_REDIS_PORT'")
+}
+
+func TestWriteEnvFileAndJSON(t *testing.T) {
+	a := assert.New(t)
+	env := newValuesTestEnvironment(a)
+
+	dir, err := ioutil.TempDir("", "docker-it-values")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+
+	envFile := filepath.Join(dir, "it.env")
+	a.Nil(env.WriteEnvFile(envFile, nil))
+	data, err := ioutil.ReadFile(envFile)
+	a.Nil(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	a.Contains(lines, "IT_REDIS_HOST=10.0.0.1")
+	a.Contains(lines, "IT_REDIS_PORT=16379")
+	a.Contains(lines, "IT_REDIS_SENTINEL_PORT=36379")
+
+	jsonFile := filepath.Join(dir, "it.json")
+	a.Nil(env.WriteJSON(jsonFile, nil))
+	data, err = ioutil.ReadFile(jsonFile)
+	a.Nil(err)
+	var values map[string]string
+	a.Nil(json.Unmarshal(data, &values))
+	a.Equal(env.Values(), values)
+}
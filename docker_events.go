@@ -0,0 +1,174 @@
+package dockerit
+
+import (
+	"context"
+	"fmt"
+	"github.com/docker/docker/api/types/events"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	eventBufferSize = 100
+)
+
+// EventType is the type of a component container event
+type EventType string
+
+const (
+	// EventStart is sent when the container was started
+	EventStart EventType = "start"
+	// EventDie is sent when the container process exited
+	EventDie EventType = "die"
+	// EventOOM is sent when the container ran out of memory
+	EventOOM EventType = "oom"
+	// EventHealthStatus is sent when the health status of the container changed
+	EventHealthStatus EventType = "health_status"
+	// EventRestart is sent when the container was restarted
+	EventRestart EventType = "restart"
+)
+
+// Event is a docker event of a component container
+type Event struct {
+	// Event type
+	Type EventType
+	// Name of the docker component
+	ComponentName string
+	// Container ID
+	ContainerID string
+	// Exit code of the container process, set for EventDie
+	ExitCode int
+	// Health status e.g. "healthy" or "unhealthy", set for EventHealthStatus
+	HealthStatus string
+	// Whether the container was stopped or destroyed by the environment, set for EventDie
+	Expected bool
+	// Time of the event
+	Time time.Time
+}
+
+// TestReporter reports test failures, e.g. *testing.T
+type TestReporter interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Events subscribes to the docker events of the environment containers until the context is done. The channel is closed
+// when the context is done, the environment is closed or the docker events stream failed. Cancel the context
+// when the events are not read anymore, the subscription is kept until then.
+func (r *DockerEnvironment) Events(ctx context.Context) <-chan Event {
+	return r.lifecycleHandler.events(ctx)
+}
+
+// Watch calls the handler when a component container dies unexpectedly, i.e. it was not stopped or destroyed by the environment.
+// The returned function stops watching, after it returns the handler is not called anymore.
+func (r *DockerEnvironment) Watch(handler func(Event)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	return watchDied(r.lifecycleHandler.events(ctx), ctx.Done(), cancel, handler)
+}
+
+// WatchTest fails the test when a component container dies unexpectedly while the test is running.
+// Call the returned function at the end of the test, e.g. defer env.WatchTest(t)()
+func (r *DockerEnvironment) WatchTest(t TestReporter) func() {
+	return r.Watch(func(event Event) {
+		t.Errorf("DockerComponent [%s] container %s died unexpectedly with exit code %d", event.ComponentName, TruncateID(event.ContainerID), event.ExitCode)
+	})
+}
+
+func watchDied(events <-chan Event, done <-chan struct{}, cancel context.CancelFunc, handler func(Event)) func() {
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if event.Type == EventDie && !event.Expected {
+					handler(event)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-finished
+	}
+}
+
+// events subscribes to the container events of the environment until the context is done or the handler is closed
+func (r *dockerLifecycleHandler) events(ctx context.Context) <-chan Event {
+	result := make(chan Event, eventBufferSize)
+	// subscribe before returning, so that no event following the call is missed
+	ctx, cancel := context.WithCancel(ctx)
+	messages, errs := r.runtime.Events(ctx, fmt.Sprintf("%s=%s", LabelEnvironmentID, r.context.ID))
+	go func() {
+		defer close(result)
+		defer cancel()
+
+		for {
+			select {
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				event, ok := r.newEvent(message)
+				if !ok {
+					continue
+				}
+				select {
+				case result <- event:
+				case <-ctx.Done():
+					return
+				case <-r.closed:
+					return
+				}
+			case err := <-errs:
+				if err != nil {
+					r.context.logger.Error.Println("Events error", err)
+				}
+				return
+			case <-ctx.Done():
+				return
+			case <-r.closed:
+				return
+			}
+		}
+	}()
+	return result
+}
+
+// newEvent converts the docker event message, other than the supported event types are skipped
+func (r *dockerLifecycleHandler) newEvent(message events.Message) (Event, bool) {
+	action := message.Action
+	if action == "" {
+		action = message.Status
+	}
+	event := Event{
+		ComponentName: message.Actor.Attributes[LabelComponent],
+		ContainerID:   message.Actor.ID,
+		Time:          time.Unix(message.Time, 0),
+	}
+	if message.TimeNano != 0 {
+		event.Time = time.Unix(0, message.TimeNano)
+	}
+	switch {
+	case action == string(EventStart), action == string(EventOOM), action == string(EventRestart):
+		event.Type = EventType(action)
+	case action == string(EventDie):
+		event.Type = EventDie
+		event.ExitCode, _ = strconv.Atoi(message.Actor.Attributes["exitCode"])
+		if container, err := r.context.getContainer(event.ComponentName); err == nil {
+			event.Expected = atomic.LoadInt32(&container.stopRequested) == 1
+		}
+	case strings.HasPrefix(action, string(EventHealthStatus)):
+		event.Type = EventHealthStatus
+		event.HealthStatus = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(action, string(EventHealthStatus)), ":"))
+	default:
+		return Event{}, false
+	}
+	return event, true
+}
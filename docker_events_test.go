@@ -0,0 +1,66 @@
+package dockerit
+
+import (
+	"context"
+	"github.com/docker/docker/api/types/events"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewEvent(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	container, err := context.addContainer(DockerComponent{Name: "it-db", Image: "postgres"})
+	a.Nil(err)
+	handler := &dockerLifecycleHandler{context: context}
+
+	message := func(action string, attributes map[string]string) events.Message {
+		attributes[LabelComponent] = "it-db"
+		return events.Message{Type: events.ContainerEventType, Action: action, Actor: events.Actor{ID: "4a5f0e1c2b3d", Attributes: attributes}, TimeNano: 1500000000000000000}
+	}
+
+	event, ok := handler.newEvent(message("die", map[string]string{"exitCode": "137"}))
+	a.True(ok)
+	a.Equal(Event{Type: EventDie, ComponentName: "it-db", ContainerID: "4a5f0e1c2b3d", ExitCode: 137, Time: time.Unix(0, 1500000000000000000)}, event)
+
+	container.stopRequested = 1
+	event, ok = handler.newEvent(message("die", map[string]string{"exitCode": "0"}))
+	a.True(ok)
+	a.True(event.Expected)
+
+	event, ok = handler.newEvent(message("health_status: unhealthy", map[string]string{}))
+	a.True(ok)
+	a.Equal(EventHealthStatus, event.Type)
+	a.Equal("unhealthy", event.HealthStatus)
+
+	for _, action := range []string{"start", "oom", "restart"} {
+		event, ok = handler.newEvent(message(action, map[string]string{}))
+		a.True(ok)
+		a.Equal(EventType(action), event.Type)
+	}
+
+	_, ok = handler.newEvent(message("exec_start: ls", map[string]string{}))
+	a.False(ok)
+}
+
+func TestWatchDied(t *testing.T) {
+	a := assert.New(t)
+
+	events := make(chan Event, 3)
+	events <- Event{Type: EventStart, ComponentName: "it-db"}
+	events <- Event{Type: EventDie, ComponentName: "it-db", Expected: true}
+	events <- Event{Type: EventDie, ComponentName: "it-redis", ExitCode: 1}
+
+	handled := make(chan Event, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := watchDied(events, ctx.Done(), cancel, func(event Event) {
+		handled <- event
+	})
+	a.Equal(Event{Type: EventDie, ComponentName: "it-redis", ExitCode: 1}, <-handled)
+	stop()
+	stop()
+	a.Len(handled, 0)
+}
@@ -0,0 +1,14 @@
+package dockerit
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+var imageArchiveNameReplacer = strings.NewReplacer("/", "_", ":", "_", "@", "_")
+
+// ImageArchivePath provides the path of the image archive in the directory as written by DockerEnvironment.SaveImages,
+// e.g. <dir>/postgres_9.6.tar for the postgres:9.6 image
+func ImageArchivePath(dir string, image string) string {
+	return filepath.Join(dir, imageArchiveNameReplacer.Replace(image)+".tar")
+}
@@ -0,0 +1,31 @@
+package dockerit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageArchivePath(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(filepath.Join("cache", "redis.tar"), ImageArchivePath("cache", "redis"))
+	a.Equal(filepath.Join("cache", "postgres_9.6.tar"), ImageArchivePath("cache", "postgres:9.6"))
+	a.Equal(filepath.Join("cache", "docker.elastic.co_elasticsearch_elasticsearch_5.5.0.tar"),
+		ImageArchivePath("cache", "docker.elastic.co/elasticsearch/elasticsearch:5.5.0"))
+}
+
+func TestDistinctImageOptions(t *testing.T) {
+	a := assert.New(t)
+
+	containers := []*dockerContainer{
+		newDockerContainer(DockerComponent{Name: "it-redis", Image: "redis", PullPolicy: PullNever}),
+		newDockerContainer(DockerComponent{Name: "it-postgres", Image: "postgres:9.6", PullPolicy: PullIfNotPresent}),
+		newDockerContainer(DockerComponent{Name: "it-redis2", Image: "redis", PullPolicy: PullIfNotPresent, ImageArchive: "cache/redis.tar"}),
+		newDockerContainer(DockerComponent{Name: "it-postgres2", Image: "postgres:9.6", PullPolicy: PullNever}),
+	}
+	images, options := getDistinctImageOptions(containers, func(image string) string { return image })
+	a.Equal([]string{"redis", "postgres:9.6"}, images)
+	a.Equal(imageOptions{pullPolicy: PullIfNotPresent, archive: "cache/redis.tar"}, options["redis"])
+	a.Equal(imageOptions{pullPolicy: PullIfNotPresent}, options["postgres:9.6"])
+}
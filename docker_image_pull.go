@@ -0,0 +1,169 @@
+package dockerit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/pkg/jsonmessage"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	pullProgressInterval = 5 * time.Second
+)
+
+type layerProgress struct {
+	status     string
+	downloaded int64
+	size       int64
+}
+
+// pullProgress aggregates the image pull progress stream and logs it periodically
+type pullProgress struct {
+	image    string
+	logger   *log.Logger
+	interval time.Duration
+	layers   map[string]*layerProgress
+	lastLog  time.Time
+}
+
+func newPullProgress(image string, logger *log.Logger) *pullProgress {
+	return &pullProgress{
+		image:    image,
+		logger:   logger,
+		interval: pullProgressInterval,
+		layers:   make(map[string]*layerProgress),
+		lastLog:  time.Now(),
+	}
+}
+
+// decode reads the JSON progress stream and returns the error embedded in the stream
+func (r *pullProgress) decode(reader io.Reader) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		var message jsonmessage.JSONMessage
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if message.Error != nil {
+			return fmt.Errorf("Pull image %s failed: %s", r.image, message.Error.Message)
+		}
+		if message.ErrorMessage != "" {
+			return fmt.Errorf("Pull image %s failed: %s", r.image, message.ErrorMessage)
+		}
+		r.update(&message)
+	}
+}
+
+func (r *pullProgress) update(message *jsonmessage.JSONMessage) {
+	if message.ID == "" || strings.HasPrefix(message.Status, "Pulling from") {
+		// e.g. "Digest: sha256:..." or "Status: Downloaded newer image for redis:latest"
+		if message.Status != "" && r.logger != nil {
+			r.logger.Println("Pulling image", r.image, message.Status)
+		}
+		return
+	}
+	layer, ok := r.layers[message.ID]
+	if !ok {
+		layer = &layerProgress{}
+		r.layers[message.ID] = layer
+	}
+	layer.status = message.Status
+	switch message.Status {
+	case "Downloading":
+		if message.Progress != nil {
+			layer.downloaded = message.Progress.Current
+			layer.size = message.Progress.Total
+		}
+	case "Verifying Checksum", "Download complete", "Extracting", "Pull complete":
+		layer.downloaded = layer.size
+	}
+	if r.logger != nil && time.Since(r.lastLog) >= r.interval {
+		r.lastLog = time.Now()
+		r.logger.Println("Pulling image", r.image, r.String())
+	}
+}
+
+// implements fmt.Stringer
+func (r *pullProgress) String() string {
+	var complete int
+	var downloaded, size int64
+	for _, layer := range r.layers {
+		if layer.status == "Pull complete" || layer.status == "Already exists" {
+			complete++
+		}
+		downloaded += layer.downloaded
+		size += layer.size
+	}
+	return fmt.Sprintf("%d/%d layers complete, downloaded %s/%s", complete, len(r.layers), formatBytes(downloaded), formatBytes(size))
+}
+
+func formatBytes(n int64) string {
+	const unit = 1000
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "kMGTPE"[exp])
+}
+
+// parsePlatform parses platform os[/arch[/variant]], nil is returned for the empty platform
+func parsePlatform(platform string) (*specs.Platform, error) {
+	if platform == "" {
+		return nil, nil
+	}
+	parts := strings.Split(platform, "/")
+	if len(parts) > 3 || parts[0] == "" || (len(parts) > 1 && parts[1] == "") {
+		return nil, errors.New("Platform '" + platform + "' is invalid, expected os[/arch[/variant]]")
+	}
+	result := &specs.Platform{OS: parts[0]}
+	if len(parts) > 1 {
+		result.Architecture = parts[1]
+	}
+	if len(parts) > 2 {
+		result.Variant = parts[2]
+	}
+	return result, nil
+}
+
+// platformMatches checks the image platform against the required one. The variant is compared only if it is required,
+// the default variants v8 of arm64 and v7 of arm are assumed if the image does not report one.
+func platformMatches(required *specs.Platform, os string, architecture string, variant string) bool {
+	if required == nil {
+		return true
+	}
+	if required.OS != os {
+		return false
+	}
+	if required.Architecture == "" {
+		return true
+	}
+	if required.Architecture != architecture {
+		return false
+	}
+	return required.Variant == "" || normalizeVariant(architecture, required.Variant) == normalizeVariant(architecture, variant)
+}
+
+func normalizeVariant(architecture string, variant string) string {
+	variant = strings.TrimPrefix(variant, "v")
+	if variant == "" {
+		switch architecture {
+		case "arm64":
+			variant = "8"
+		case "arm":
+			variant = "7"
+		}
+	}
+	return variant
+}
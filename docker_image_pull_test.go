@@ -0,0 +1,89 @@
+package dockerit
+
+import (
+	"bytes"
+	specs "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestPullProgressDecode(t *testing.T) {
+	a := assert.New(t)
+
+	stream := `{"status":"Pulling from library/redis","id":"latest"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a1"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"b2"}
+{"status":"Downloading","progressDetail":{"current":1500,"total":3000},"id":"a1"}
+{"status":"Downloading","progressDetail":{"current":2000000,"total":4000000},"id":"b2"}
+{"status":"Download complete","progressDetail":{},"id":"a1"}
+{"status":"Extracting","progressDetail":{"current":100,"total":3000},"id":"a1"}
+{"status":"Pull complete","progressDetail":{},"id":"a1"}
+{"status":"Digest: sha256:1234"}
+`
+	var out bytes.Buffer
+	progress := newPullProgress("redis", log.New(&out, "", 0))
+	progress.interval = 0
+
+	a.Nil(progress.decode(strings.NewReader(stream)))
+	a.Equal("1/2 layers complete, downloaded 2.0MB/4.0MB", progress.String())
+	a.Contains(out.String(), "Pulling image redis Pulling from library/redis\n")
+	a.Contains(out.String(), "Pulling image redis 0/2 layers complete, downloaded 1.5kB/3.0kB\n")
+	a.Contains(out.String(), "Pulling image redis Digest: sha256:1234\n")
+}
+
+func TestPullProgressDecodeError(t *testing.T) {
+	a := assert.New(t)
+
+	stream := `{"status":"Pulling from library/redis","id":"latest"}
+{"errorDetail":{"message":"unauthorized: authentication required"},"error":"unauthorized: authentication required"}
+`
+	progress := newPullProgress("my/private", nil)
+	err := progress.decode(strings.NewReader(stream))
+	a.EqualError(err, "Pull image my/private failed: unauthorized: authentication required")
+}
+
+func TestFormatBytes(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("999B", formatBytes(999))
+	a.Equal("1.5kB", formatBytes(1500))
+	a.Equal("42.0MB", formatBytes(42000000))
+	a.Equal("1.2GB", formatBytes(1200000000))
+}
+
+func TestParsePlatform(t *testing.T) {
+	a := assert.New(t)
+
+	platform, err := parsePlatform("linux/arm64/v8")
+	a.Nil(err)
+	a.Equal(&specs.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, platform)
+
+	platform, err = parsePlatform("windows")
+	a.Nil(err)
+	a.Equal(&specs.Platform{OS: "windows"}, platform)
+
+	platform, err = parsePlatform("")
+	a.Nil(err)
+	a.Nil(platform)
+
+	_, err = parsePlatform("/amd64")
+	a.EqualError(err, "Platform '/amd64' is invalid, expected os[/arch[/variant]]")
+
+	_, err = parsePlatform("linux/arm/v7/extra")
+	a.NotNil(err)
+}
+
+func TestPlatformMatches(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(platformMatches(nil, "linux", "amd64", ""))
+	a.True(platformMatches(&specs.Platform{OS: "linux"}, "linux", "arm64", ""))
+	a.False(platformMatches(&specs.Platform{OS: "windows"}, "linux", "amd64", ""))
+	a.False(platformMatches(&specs.Platform{OS: "linux", Architecture: "arm64"}, "linux", "amd64", ""))
+	a.True(platformMatches(&specs.Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}, "linux", "arm64", ""))
+	a.True(platformMatches(&specs.Platform{OS: "linux", Architecture: "arm", Variant: "v7"}, "linux", "arm", "7"))
+	a.False(platformMatches(&specs.Platform{OS: "linux", Architecture: "arm", Variant: "v6"}, "linux", "arm", ""))
+	a.True(platformMatches(&specs.Platform{OS: "linux", Architecture: "arm"}, "linux", "arm", "v6"))
+}
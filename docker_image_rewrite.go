@@ -0,0 +1,84 @@
+package dockerit
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// registry mirrors in format [registry=]mirror[,registry=mirror...], the registry defaults to docker.io
+	registryMirrorEnv = "DOCKER_IT_REGISTRY_MIRROR"
+)
+
+// ImageRewriter rewrites an image name before the image is checked, loaded, pulled or used by a container
+type ImageRewriter func(image string) string
+
+// NewRegistryMirrorRewriter creates an ImageRewriter replacing registry prefixes of fully qualified image names with
+// mirror prefixes, e.g. the mapping {"docker.io": "mirror.example.com/hub"} rewrites "redis" to
+// "mirror.example.com/hub/library/redis:latest". The longest matching prefix is used. Image names without
+// matching prefix are not changed.
+func NewRegistryMirrorRewriter(mirrors map[string]string) ImageRewriter {
+	prefixes := make(map[string]string)
+	for prefix, mirror := range mirrors {
+		prefixes[strings.TrimSuffix(prefix, "/")] = strings.TrimSuffix(mirror, "/")
+	}
+	return func(image string) string {
+		normalized := normalizeImageName(image)
+		var matched string
+		for prefix := range prefixes {
+			if (normalized == prefix || strings.HasPrefix(normalized, prefix+"/")) && len(prefix) > len(matched) {
+				matched = prefix
+			}
+		}
+		if matched == "" {
+			return image
+		}
+		return prefixes[matched] + normalized[len(matched):]
+	}
+}
+
+// normalizeImageName provides the fully qualified image name, adding implicit docker.io registry,
+// library namespace and latest tag, e.g. "redis" is normalized to "docker.io/library/redis:latest"
+func normalizeImageName(image string) string {
+	name, digest := image, ""
+	if i := strings.IndexRune(name, '@'); i >= 0 {
+		name, digest = name[:i], name[i:]
+	}
+	tag := ""
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i:]
+	}
+	hostname, path := defaultRegistryHostname, name
+	if i := strings.IndexRune(name, '/'); i >= 0 {
+		if first := name[:i]; strings.ContainsAny(first, ".:") || first == "localhost" {
+			hostname, path = normalizeRegistryAddress(first), name[i+1:]
+		}
+	}
+	if hostname == defaultRegistryHostname && !strings.ContainsRune(path, '/') {
+		path = "library/" + path
+	}
+	if tag == "" && digest == "" {
+		tag = ":latest"
+	}
+	return hostname + "/" + path + tag + digest
+}
+
+// parseRegistryMirrors parses mirrors in format [registry=]mirror[,registry=mirror...]
+func parseRegistryMirrors(value string) (map[string]string, error) {
+	mirrors := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prefix, mirror := defaultRegistryHostname, entry
+		if i := strings.IndexRune(entry, '='); i >= 0 {
+			prefix, mirror = strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+		}
+		if prefix == "" || mirror == "" {
+			return nil, fmt.Errorf("Registry mirror '%s' is invalid, expected [registry=]mirror", entry)
+		}
+		mirrors[prefix] = mirror
+	}
+	return mirrors, nil
+}
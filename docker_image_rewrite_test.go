@@ -0,0 +1,61 @@
+package dockerit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestNormalizeImageName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("docker.io/library/redis:latest", normalizeImageName("redis"))
+	a.Equal("docker.io/library/postgres:9.6", normalizeImageName("postgres:9.6"))
+	a.Equal("docker.io/spotify/kafka:latest", normalizeImageName("spotify/kafka"))
+	a.Equal("docker.io/library/redis:latest", normalizeImageName("docker.io/redis"))
+	a.Equal("docker.io/library/redis:4", normalizeImageName("index.docker.io/library/redis:4"))
+	a.Equal("docker.elastic.co/elasticsearch/elasticsearch:5.5.0", normalizeImageName("docker.elastic.co/elasticsearch/elasticsearch:5.5.0"))
+	a.Equal("localhost:5000/my-app:latest", normalizeImageName("localhost:5000/my-app"))
+	a.Equal("docker.io/library/redis@sha256:abc", normalizeImageName("redis@sha256:abc"))
+}
+
+func TestRegistryMirrorRewriter(t *testing.T) {
+	a := assert.New(t)
+
+	rewrite := NewRegistryMirrorRewriter(map[string]string{
+		"docker.io":          "mirror.example.com/hub/",
+		"docker.io/library":  "mirror.example.com/official",
+		"docker.elastic.co/": "mirror.example.com/elastic",
+	})
+	a.Equal("mirror.example.com/official/redis:latest", rewrite("redis"))
+	a.Equal("mirror.example.com/official/postgres:9.6", rewrite("postgres:9.6"))
+	a.Equal("mirror.example.com/hub/spotify/kafka:latest", rewrite("spotify/kafka"))
+	a.Equal("mirror.example.com/elastic/elasticsearch/elasticsearch:5.5.0", rewrite("docker.elastic.co/elasticsearch/elasticsearch:5.5.0"))
+	a.Equal("quay.io/coreos/etcd", rewrite("quay.io/coreos/etcd"))
+}
+
+func TestParseRegistryMirrors(t *testing.T) {
+	a := assert.New(t)
+
+	mirrors, err := parseRegistryMirrors("mirror.example.com/hub, quay.io=mirror.example.com/quay")
+	a.Nil(err)
+	a.Equal(map[string]string{"docker.io": "mirror.example.com/hub", "quay.io": "mirror.example.com/quay"}, mirrors)
+
+	_, err = parseRegistryMirrors("quay.io=")
+	a.EqualError(err, "Registry mirror 'quay.io=' is invalid, expected [registry=]mirror")
+}
+
+func TestRegistryMirrorEnvironmentVariable(t *testing.T) {
+	a := assert.New(t)
+
+	os.Setenv(registryMirrorEnv, "mirror.example.com/hub")
+	defer os.Unsetenv(registryMirrorEnv)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	a.Equal("mirror.example.com/hub/library/redis:latest", context.rewriteImage("redis"))
+
+	os.Setenv(registryMirrorEnv, "=mirror.example.com/hub")
+	_, err = newDockerEnvironmentContext()
+	a.NotNil(err)
+}
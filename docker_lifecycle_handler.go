@@ -1,33 +1,88 @@
 package dockerit
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	healthcheckPollInterval = 500 * time.Millisecond
+
+	// docker defaults of the health check
+	defaultHealthcheckInterval = 30 * time.Second
+	defaultHealthcheckTimeout  = 30 * time.Second
+	defaultHealthcheckRetries  = 3
+)
+
+// source of a bind, which is a named volume and not a host path
+var namedVolume = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
 type dockerLifecycleHandler struct {
-	dockerClient *dockerClient
-	context      *dockerEnvironmentContext
+	runtime Runtime
+	context *dockerEnvironmentContext
+	// whether the runtime was created by this handler, a runtime given with WithRuntime is not closed
+	ownsRuntime bool
+
+	// image pulls and loads of this handler
+	imageTasksMutex sync.Mutex
+	imageTasks      map[string]*imageTask
+
+	// closed when the handler is closed
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	// network of the containers and whether it was created by this handler
+	networkMutex   sync.Mutex
+	networkID      string
+	networkCreated bool
+
+	// serializes the creation of the named volumes
+	volumesMutex sync.Mutex
+}
+
+// imageTask is an image pull or load in progress or completed
+type imageTask struct {
+	done chan struct{}
+	err  error
 }
 
 func newDockerLifecycleHandler(context *dockerEnvironmentContext) (*dockerLifecycleHandler, error) {
-	dockerClient, err := newDockerClient()
-	if err != nil {
-		return nil, err
+	runtime := context.runtime
+	ownsRuntime := runtime == nil
+	if ownsRuntime {
+		dockerClient, err := newDockerClient(context.clientConfig)
+		if err != nil {
+			return nil, err
+		}
+		runtime = dockerClient
 	}
-	return &dockerLifecycleHandler{dockerClient: dockerClient, context: context}, nil
+	return &dockerLifecycleHandler{runtime: runtime, context: context, ownsRuntime: ownsRuntime, imageTasks: make(map[string]*imageTask), closed: make(chan struct{})}, nil
 }
 
 func (r *dockerLifecycleHandler) Close() {
 	r.context.logger.Info.Println("Closing docker lifecycle handler")
+	r.closeOnce.Do(func() { close(r.closed) })
 
 	for _, container := range r.context.containers {
 		container.stopFollowLogs()
 	}
-	r.dockerClient.Close()
+	if r.ownsRuntime {
+		r.runtime.Close()
+	}
 }
 
 func (r *dockerLifecycleHandler) Create(container *dockerContainer) error {
@@ -37,12 +92,25 @@ func (r *dockerLifecycleHandler) Create(container *dockerContainer) error {
 		r.context.logger.Info.Println("Component", container.Name, "already exists, container", TruncateID(container.containerID))
 		return nil
 	}
+	// runtime values of the components created or started before
+	if err := r.context.getValueResolver().configureContainerRuntime(container); err != nil {
+		return err
+	}
+	if err := r.callHooks(container, "BeforeCreate", container.Hooks.BeforeCreate); err != nil {
+		return err
+	}
 
-	if err := r.checkOrPullDockerImage(container.Image, container.ForcePull); err != nil {
+	pullStart := time.Now()
+	err := r.checkOrPullDockerImage(r.context.rewriteImage(container.resolved.image), container.getImageOptions())
+	container.phaseDurations.record(PhasePull, pullStart)
+	if err != nil {
 		return err
 	}
 
-	if err := r.createDockerContainer(container); err != nil {
+	createStart := time.Now()
+	err = r.createDockerContainer(container)
+	container.phaseDurations.record(PhaseCreate, createStart)
+	if err != nil {
 		return err
 	}
 	r.context.logger.Info.Println("Created new container", TruncateID(container.containerID), "for", container.Name)
@@ -51,6 +119,13 @@ func (r *dockerLifecycleHandler) Create(container *dockerContainer) error {
 }
 
 func (r *dockerLifecycleHandler) Start(container *dockerContainer) error {
+	if err := r.start(container); err != nil {
+		return r.newStartError(container, err)
+	}
+	return nil
+}
+
+func (r *dockerLifecycleHandler) start(container *dockerContainer) error {
 	r.context.logger.Info.Println("Start component", container.Name)
 
 	if container.containerID == "" {
@@ -65,20 +140,47 @@ func (r *dockerLifecycleHandler) Start(container *dockerContainer) error {
 		return nil
 	}
 
+	if err := r.callHooks(container, "BeforeStart", container.Hooks.BeforeStart); err != nil {
+		return err
+	}
 	r.context.logger.Info.Println("Starting container", TruncateID(container.containerID), "for", container.Name)
-	if err := r.dockerClient.StartContainer(container.containerID); err != nil {
+	atomic.StoreInt32(&container.stopRequested, 0)
+	container.startedAt = time.Now()
+	err := r.runtime.StartContainer(container.containerID)
+	container.phaseDurations.record(PhaseStart, container.startedAt)
+	if err != nil {
 		// try to fetch logs from container
-		out := stdoutWriter(container.Name)
-		r.fetchLogs(container.containerID, out, out)
+		r.fetchContainerLogs(container)
+		return err
+	}
+	if err := r.updateContainerIP(container); err != nil {
 		return err
 	}
 	if container.FollowLogs {
-		out := stdoutWriter(container.Name)
-		if err := r.followLogs(container, out, out); err != nil {
+		if err := r.followLogs(container); err != nil {
 			return err
 		}
 	}
-	if container.AfterStart != nil {
+	if err := r.wait(container); err != nil {
+		return err
+	}
+	return r.callHooks(container, "AfterStart", container.Hooks.AfterStart)
+}
+
+// wait waits for the healthy container and invokes the AfterStart callback
+func (r *dockerLifecycleHandler) wait(container *dockerContainer) error {
+	defer container.phaseDurations.record(PhaseWait, time.Now())
+
+	if container.resolved.healthcheck != nil && !container.resolved.healthcheck.disabled() {
+		if err := r.waitForHealthy(container); err != nil {
+			return err
+		}
+	}
+	if callback, ok := container.AfterStart.(ComponentCallback); ok {
+		if err := callback.CallComponent(r.newComponentHandle(container)); err != nil {
+			return err
+		}
+	} else if container.AfterStart != nil {
 		if err := container.AfterStart.Call(container.Name, r.context); err != nil {
 			return err
 
@@ -87,6 +189,69 @@ func (r *dockerLifecycleHandler) Start(container *dockerContainer) error {
 	return nil
 }
 
+// callHooks invokes the hooks of a lifecycle phase in order, the first failed hook stops the invocation
+func (r *dockerLifecycleHandler) callHooks(container *dockerContainer, phase string, hooks []Hook) error {
+	component := r.newComponentHandle(container)
+	for i, hook := range hooks {
+		if err := hook.Call(container.Name, r.context, component); err != nil {
+			return fmt.Errorf("DockerComponent [%s] %s hook %d failed: %v", container.Name, phase, i, err)
+		}
+	}
+	return nil
+}
+
+// callAllHooks invokes all hooks of a lifecycle phase in order, even if a hook failed. The first error is returned.
+func (r *dockerLifecycleHandler) callAllHooks(container *dockerContainer, phase string, hooks []Hook) error {
+	component := r.newComponentHandle(container)
+	var result error
+	for i, hook := range hooks {
+		if err := hook.Call(container.Name, r.context, component); err != nil {
+			err = fmt.Errorf("DockerComponent [%s] %s hook %d failed: %v", container.Name, phase, i, err)
+			r.context.logger.Error.Println(err)
+			if result == nil {
+				result = err
+			}
+		}
+	}
+	return result
+}
+
+// waitForHealthy waits until docker reports the container as healthy. The wait is limited by the health check
+// start period, interval, timeout and retries.
+func (r *dockerLifecycleHandler) waitForHealthy(container *dockerContainer) error {
+	r.context.logger.Info.Println("Waiting for healthy container", TruncateID(container.containerID), "for", container.Name)
+	timeout := container.resolved.healthcheck.waitTimeout()
+	deadline := time.Now().Add(timeout)
+	for {
+		inspect, err := r.runtime.InspectContainer(container.containerID)
+		if err != nil {
+			return err
+		}
+		state := inspect.State
+		if state == nil || !state.Running {
+			return fmt.Errorf("DockerComponent [%s] container is not running", container.Name)
+		}
+		if state.Health == nil {
+			return fmt.Errorf("DockerComponent [%s] container has no health check, the image does not define HEALTHCHECK", container.Name)
+		}
+		switch state.Health.Status {
+		case "healthy":
+			r.context.logger.Info.Println("Container", TruncateID(container.containerID), "for", container.Name, "is healthy")
+			return nil
+		case "unhealthy":
+			return fmt.Errorf("DockerComponent [%s] container is unhealthy", container.Name)
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("DockerComponent [%s] container is not healthy after %v, health status %s", container.Name, timeout, state.Health.Status)
+		}
+		if remaining > healthcheckPollInterval {
+			remaining = healthcheckPollInterval
+		}
+		time.Sleep(remaining)
+	}
+}
+
 func (r *dockerLifecycleHandler) Stop(container *dockerContainer) error {
 	r.context.logger.Info.Println("Stop component", container.Name)
 
@@ -96,8 +261,21 @@ func (r *dockerLifecycleHandler) Stop(container *dockerContainer) error {
 	if result, err := r.isContainerRunning(container.containerID); err != nil {
 		return err
 	} else if result {
-		return r.dockerClient.StopContainer(container.containerID)
+		hookErr := r.callAllHooks(container, "BeforeStop", container.Hooks.BeforeStop)
+		atomic.StoreInt32(&container.stopRequested, 1)
+		stopStart := time.Now()
+		err := r.runtime.StopContainer(container.containerID, r.context.stopTimeout)
+		container.phaseDurations.record(PhaseStop, stopStart)
+		if err != nil {
+			return err
+		}
+		container.setIP("")
+		if err := r.callAllHooks(container, "AfterStop", container.Hooks.AfterStop); hookErr == nil {
+			hookErr = err
+		}
+		return hookErr
 	}
+	container.setIP("")
 	return nil
 }
 
@@ -116,32 +294,223 @@ func (r *dockerLifecycleHandler) Destroy(container *dockerContainer) error {
 		return nil
 	}
 
+	hookErr := r.callAllHooks(container, "BeforeDestroy", container.Hooks.BeforeDestroy)
+
 	if running, err := r.isContainerRunning(container.containerID); err != nil {
 		return err
 	} else if running {
-		r.Stop(container)
+		if err := r.Stop(container); err != nil && hookErr == nil {
+			hookErr = err
+		}
 	}
 
 	r.context.logger.Info.Println("Remove container", TruncateID(container.containerID))
-	if err := r.dockerClient.RemoveContainer(container.containerID); err != nil {
+	atomic.StoreInt32(&container.stopRequested, 1)
+	removeStart := time.Now()
+	err := r.runtime.RemoveContainer(container.containerID)
+	container.phaseDurations.record(PhaseRemove, removeStart)
+	if err != nil {
 		return err
 	}
-	container.containerID = ""
+	container.setContainer("", "")
 
 	if container.RemoveImageAfterDestroy {
-		r.context.logger.Info.Println("Remove image", container.Image)
-		if err := r.dockerClient.RemoveImageByName(container.Image); err != nil {
+		image := r.context.rewriteImage(container.resolved.image)
+		r.context.logger.Info.Println("Remove image", image)
+		r.forgetPullImageTasks(image)
+		if err := r.runtime.RemoveImageByName(image); err != nil {
 			return err
 		}
 	}
+	return hookErr
+}
+
+// DumpLogs writes the state and the log output of the containers. A container whose logs cannot be fetched
+// does not stop the dump, the error is written inline.
+func (r *dockerLifecycleHandler) DumpLogs(w io.Writer, containers []*dockerContainer) error {
+	for _, container := range containers {
+		if container.containerID == "" {
+			fmt.Fprintf(w, "==== DockerComponent [%s] container was not created ====\n", container.Name)
+			continue
+		}
+		var state string
+		if inspect, err := r.runtime.InspectContainer(container.containerID); err != nil {
+			state = err.Error()
+		} else {
+			state = describeContainerState(inspect.State)
+		}
+		fmt.Fprintf(w, "==== DockerComponent [%s] container %s state: %s ====\n", container.Name, TruncateID(container.containerID), state)
+		if err := r.copyLogs(container.containerID, container.LogTimestamps, w, w); err != nil {
+			fmt.Fprintf(w, "==== DockerComponent [%s] logs error: %v ====\n", container.Name, err)
+		}
+	}
 	return nil
 }
 
+// Attach adopts the existing container of the component, e.g. created by another process with the same environment ID.
+// The host ports of the component are taken from the container.
+func (r *dockerLifecycleHandler) Attach(container *dockerContainer) error {
+	existing, err := r.runtime.GetContainerByName(r.getContainerName(container.Name))
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return nil
+	}
+	inspect, err := r.runtime.InspectContainer(existing.ID)
+	if err != nil {
+		return err
+	}
+	if inspect.HostConfig != nil {
+		hostPorts := getHostPorts(inspect.HostConfig.PortBindings)
+		for i, port := range container.portBindings {
+			if hostPort, ok := hostPorts[port.ContainerPort]; ok {
+				container.portBindings[i].HostPort = hostPort
+			}
+		}
+	}
+	container.setContainer(existing.ID, r.getContainerName(container.Name))
+	if inspect.State != nil && inspect.State.Running {
+		container.setIP(getContainerIP(inspect))
+	}
+	r.context.logger.Info.Println("Attached container", TruncateID(container.containerID), "for", container.Name)
+	return nil
+}
+
+// getHostPorts maps the container ports to the bound host ports
+func getHostPorts(portBindings nat.PortMap) map[int]int {
+	hostPorts := make(map[int]int)
+	for port, bindings := range portBindings {
+		for _, binding := range bindings {
+			if hostPort, err := strconv.Atoi(binding.HostPort); err == nil {
+				hostPorts[port.Int()] = hostPort
+				break
+			}
+		}
+	}
+	return hostPorts
+}
+
+// Status describes the container of the component
+func (r *dockerLifecycleHandler) Status(container *dockerContainer) (ComponentStatus, error) {
+	status := ComponentStatus{Name: container.Name, ContainerID: container.containerID, Ports: container.portBindings}
+	if container.containerID == "" {
+		return status, nil
+	}
+	inspect, err := r.runtime.InspectContainer(container.containerID)
+	if err != nil {
+		return status, err
+	}
+	status.State = describeContainerState(inspect.State)
+	return status, nil
+}
+
+// Exec runs a command in the running container of the component and returns its exit code
+func (r *dockerLifecycleHandler) Exec(container *dockerContainer, cmd []string, stdout, stderr io.Writer) (int, error) {
+	if container.containerID == "" {
+		return 0, fmt.Errorf("DockerComponent [%s] container was not created", container.Name)
+	}
+	if running, err := r.isContainerRunning(container.containerID); err != nil {
+		return 0, err
+	} else if !running {
+		return 0, fmt.Errorf("DockerComponent [%s] container is not running", container.Name)
+	}
+	return r.runtime.ExecContainer(container.containerID, cmd, stdout, stderr)
+}
+
+// WriteLogs writes the log output of the component container
+func (r *dockerLifecycleHandler) WriteLogs(container *dockerContainer, stdout, stderr io.Writer) error {
+	if container.containerID == "" {
+		return fmt.Errorf("DockerComponent [%s] container was not created", container.Name)
+	}
+	return r.copyLogs(container.containerID, container.LogTimestamps, stdout, stderr)
+}
+
+// Logs provides the log output lines of the component container
+func (r *dockerLifecycleHandler) Logs(container *dockerContainer, since time.Time) ([]string, error) {
+	if container.containerID == "" {
+		return nil, fmt.Errorf("DockerComponent [%s] container was not created", container.Name)
+	}
+	reader, err := r.runtime.ContainerLogs(container.containerID, false, container.LogTimestamps, since)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, reader); err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// Inspect provides the state of the component container
+func (r *dockerLifecycleHandler) Inspect(container *dockerContainer) (ContainerState, error) {
+	if container.containerID == "" {
+		return ContainerState{}, fmt.Errorf("DockerComponent [%s] container was not created", container.Name)
+	}
+	inspect, err := r.runtime.InspectContainer(container.containerID)
+	if err != nil {
+		return ContainerState{}, err
+	}
+	return newContainerState(inspect.State), nil
+}
+
+func newContainerState(state *types.ContainerState) ContainerState {
+	if state == nil {
+		return ContainerState{}
+	}
+	result := ContainerState{
+		Status:    state.Status,
+		Running:   state.Running,
+		ExitCode:  state.ExitCode,
+		OOMKilled: state.OOMKilled,
+		Error:     state.Error,
+	}
+	if state.Health != nil {
+		result.Health = state.Health.Status
+	}
+	return result
+}
+
+func (r *dockerLifecycleHandler) newComponentHandle(container *dockerContainer) *dockerComponentHandle {
+	return &dockerComponentHandle{container: container, lifecycleHandler: r}
+}
+
+func (r *dockerLifecycleHandler) newStartError(container *dockerContainer, err error) error {
+	startError := &StartError{ComponentName: container.Name, ContainerID: container.containerID, Err: err}
+	if container.containerID == "" {
+		return startError
+	}
+	if inspect, err := r.runtime.InspectContainer(container.containerID); err != nil {
+		r.context.logger.Error.Println("Inspect container error", err)
+	} else {
+		startError.State = describeContainerState(inspect.State)
+	}
+	lines := container.ErrorLogLines
+	if lines <= 0 {
+		lines = defaultErrorLogLines
+	}
+	buffer := newLogBuffer(lines)
+	stdout, stderr := buffer.writer(), buffer.writer()
+	if err := r.copyLogs(container.containerID, container.LogTimestamps, stdout, stderr); err != nil {
+		r.context.logger.Error.Println("Fetch logs error", err)
+	}
+	stdout.Flush()
+	stderr.Flush()
+	startError.Logs, _ = buffer.lines(time.Time{})
+	return startError
+}
+
 func (r *dockerLifecycleHandler) isContainerRunning(containerID string) (bool, error) {
 	if containerID == "" {
 		return false, errors.New("isContainerRunning: containerID must not be empty")
 	}
-	container, err := r.dockerClient.GetContainerByID(containerID)
+	container, err := r.runtime.GetContainerByID(containerID)
 	if err != nil {
 		return false, err
 	}
@@ -154,7 +523,7 @@ func (r *dockerLifecycleHandler) isContainerRunning(containerID string) (bool, e
 
 func (r *dockerLifecycleHandler) containerExists(containerID string) (bool, error) {
 	if containerID != "" {
-		container, err := r.dockerClient.GetContainerByID(containerID)
+		container, err := r.runtime.GetContainerByID(containerID)
 		if err != nil {
 			return false, err
 		}
@@ -165,30 +534,283 @@ func (r *dockerLifecycleHandler) containerExists(containerID string) (bool, erro
 	return false, nil
 }
 
-func (r *dockerLifecycleHandler) checkOrPullDockerImage(image string, forcePull bool) error {
-	summary, err := r.dockerClient.GetImageByName(image)
+// PullImages checks, loads or pulls distinct images of the containers concurrently, at most parallelism images at once
+func (r *dockerLifecycleHandler) PullImages(containers []*dockerContainer, parallelism int) error {
+	if parallelism <= 0 {
+		parallelism = 1
+	}
+	images, imageOptions := getDistinctImageOptions(containers, r.context.rewriteImage)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, parallelism)
+	errs := make([]error, len(images))
+	for i, image := range images {
+		wg.Add(1)
+		go func(i int, image string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			errs[i] = r.checkOrPullDockerImage(image, imageOptions[image])
+		}(i, image)
+	}
+	wg.Wait()
+
+	messages := make([]string, 0)
+	for _, err := range errs {
+		if err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) != 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// getDistinctImageOptions provides distinct rewritten images of the containers in order of appearance and their options.
+// The strongest pull policy of the containers sharing an image is used.
+func getDistinctImageOptions(containers []*dockerContainer, rewriteImage ImageRewriter) ([]string, map[string]imageOptions) {
+	images := make([]string, 0)
+	result := make(map[string]imageOptions)
+	for _, container := range containers {
+		image := rewriteImage(container.resolved.image)
+		options := container.getImageOptions()
+		current, exists := result[image]
+		if !exists {
+			images = append(images, image)
+			result[image] = options
+			continue
+		}
+		if options.pullPolicy == PullAlways || (options.pullPolicy == PullIfNotPresent && current.pullPolicy == PullNever) {
+			current.pullPolicy = options.pullPolicy
+		}
+		if current.archive == "" {
+			current.archive = options.archive
+		}
+		if current.platform == "" {
+			current.platform = options.platform
+		}
+		result[image] = current
+	}
+	return images, result
+}
+
+func (r *dockerLifecycleHandler) checkOrPullDockerImage(image string, options imageOptions) error {
+	imageExists, err := r.imageExists(image)
 	if err != nil {
 		return err
 	}
-	imageExists := summary != nil
+	if !imageExists && options.archive != "" {
+		if err := r.runImageTaskOnce(loadImageTaskKey(options.archive), func() error {
+			return r.loadImage(options.archive)
+		}); err != nil {
+			return err
+		}
+		if imageExists, err = r.imageExists(image); err != nil {
+			return err
+		} else if !imageExists {
+			return fmt.Errorf("Image archive %s does not contain image %s", options.archive, image)
+		}
+	}
 
-	if !forcePull {
+	if options.pullPolicy == PullIfNotPresent && imageExists && options.platform != "" {
+		// the image of another platform is replaced by the pulled one
+		if imageExists, err = r.imagePlatformMatches(image, options.platform); err != nil {
+			return err
+		}
+	}
+	if options.pullPolicy == PullNever || (options.pullPolicy == PullIfNotPresent && imageExists) {
 		if imageExists {
-			return nil
+			return r.checkImagePlatform(image, options.platform)
 		}
 		return fmt.Errorf("Local images %s does not exist", image)
 	}
-	r.context.logger.Info.Println("Pulling image", image)
-	if err := r.dockerClient.PullImage(image); err != nil {
+	if err := r.runImageTaskOnce(pullImageTaskKey(image, options.platform), func() error {
+		return r.pullImage(image, options.platform, options.registryAuth)
+	}); err != nil {
 		if imageExists {
-			r.context.logger.Info.Println("Image", image, "cannot be pulled, using existing one")
-			return nil
+			r.context.logger.Info.Println("Image", image, "cannot be pulled, using existing one", err)
+			return r.checkImagePlatform(image, options.platform)
 		}
 		return err
 	}
+	return r.checkImagePlatform(image, options.platform)
+}
+
+func (r *dockerLifecycleHandler) imageExists(image string) (bool, error) {
+	summary, err := r.runtime.GetImageByName(image)
+	if err != nil {
+		return false, err
+	}
+	return summary != nil, nil
+}
+
+func pullImageTaskKey(image string, platform string) string {
+	if platform == "" {
+		return "pull " + image
+	}
+	return "pull " + image + " " + platform
+}
+
+func loadImageTaskKey(archive string) string {
+	return "load " + archive
+}
+
+// runImageTaskOnce runs the task unless a task with the same key was already completed by the handler or is in progress
+func (r *dockerLifecycleHandler) runImageTaskOnce(key string, task func() error) error {
+	r.imageTasksMutex.Lock()
+	current, exists := r.imageTasks[key]
+	if exists {
+		r.imageTasksMutex.Unlock()
+		<-current.done
+		return current.err
+	}
+	current = &imageTask{done: make(chan struct{})}
+	r.imageTasks[key] = current
+	r.imageTasksMutex.Unlock()
+
+	current.err = task()
+	if current.err != nil {
+		// failed task can be retried
+		r.forgetImageTask(key)
+	}
+	close(current.done)
+	return current.err
+}
+
+func (r *dockerLifecycleHandler) forgetImageTask(key string) {
+	r.imageTasksMutex.Lock()
+	defer r.imageTasksMutex.Unlock()
+	delete(r.imageTasks, key)
+}
+
+// forgetPullImageTasks forgets the pulls of the image for all platforms
+func (r *dockerLifecycleHandler) forgetPullImageTasks(image string) {
+	r.imageTasksMutex.Lock()
+	defer r.imageTasksMutex.Unlock()
+	key := pullImageTaskKey(image, "")
+	for taskKey := range r.imageTasks {
+		if taskKey == key || strings.HasPrefix(taskKey, key+" ") {
+			delete(r.imageTasks, taskKey)
+		}
+	}
+}
+
+func (r *dockerLifecycleHandler) pullImage(image string, platform string, registryAuth *RegistryAuth) error {
+	if platform == "" {
+		r.context.logger.Info.Println("Pulling image", image)
+	} else {
+		r.context.logger.Info.Println("Pulling image", image, "platform", platform)
+	}
+	encodedAuth, err := getImageRegistryAuth(image, registryAuth, r.context.logger)
+	if err != nil {
+		return err
+	}
+	return r.runtime.PullImage(image, platform, encodedAuth, r.context.logger.Info)
+}
+
+func (r *dockerLifecycleHandler) loadImage(archive string) error {
+	r.context.logger.Info.Println("Loading image archive", archive)
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return r.runtime.LoadImage(file)
+}
+
+// SaveImages writes the images into tar archives in the directory, the images are checked or pulled first
+func (r *dockerLifecycleHandler) SaveImages(dir string, containers []*dockerContainer, parallelism int) error {
+	if err := r.PullImages(containers, parallelism); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	saved := make(map[string]struct{})
+	for _, container := range containers {
+		image := container.resolved.image
+		if _, exists := saved[image]; exists {
+			continue
+		}
+		saved[image] = struct{}{}
+		// the archive is named after the configured image, so it can be referenced by the component definition.
+		// A rewritten image is saved with the configured name too, so the archive can be loaded with or without the rewrite.
+		images := []string{image}
+		if rewritten := r.context.rewriteImage(image); rewritten != image {
+			if err := r.runtime.TagImage(rewritten, image); err != nil {
+				return err
+			}
+			images = []string{rewritten, image}
+		}
+		if err := r.saveImage(images, ImageArchivePath(dir, image)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (r *dockerLifecycleHandler) saveImage(images []string, archive string) error {
+	r.context.logger.Info.Println("Saving image", strings.Join(images, " "), "to", archive)
+	// write to a temporary file first, so an interrupted save does not leave a broken archive
+	tmp := archive + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := r.runtime.SaveImages(images, file); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, archive)
+}
+
+// checkImagePlatform verifies the local image was built for the required platform
+func (r *dockerLifecycleHandler) checkImagePlatform(image string, platform string) error {
+	if platform == "" {
+		return nil
+	}
+	required, err := parsePlatform(platform)
+	if err != nil {
+		return err
+	}
+	inspect, err := r.runtime.InspectImage(image)
+	if err != nil {
+		return err
+	}
+	if !platformMatches(required, inspect.Os, inspect.Architecture, inspect.Variant) {
+		return fmt.Errorf("Image %s platform %s does not match required platform %s", image, describePlatform(inspect), platform)
+	}
+	return nil
+}
+
+// imagePlatformMatches checks if the local image was built for the required platform
+func (r *dockerLifecycleHandler) imagePlatformMatches(image string, platform string) (bool, error) {
+	required, err := parsePlatform(platform)
+	if err != nil {
+		return false, err
+	}
+	inspect, err := r.runtime.InspectImage(image)
+	if err != nil {
+		return false, err
+	}
+	return platformMatches(required, inspect.Os, inspect.Architecture, inspect.Variant), nil
+}
+
+func describePlatform(inspect *types.ImageInspect) string {
+	platform := inspect.Os + "/" + inspect.Architecture
+	if inspect.Variant != "" {
+		platform += "/" + inspect.Variant
+	}
+	return platform
+}
+
 func (r *dockerLifecycleHandler) createDockerContainer(container *dockerContainer) error {
 	containerName := r.getContainerName(container.Name)
 
@@ -207,19 +829,72 @@ func (r *dockerLifecycleHandler) createDockerContainer(container *dockerContaine
 		}
 	}
 	cmd := make([]string, 0)
-	if container.Cmd != nil {
-		cmd = append(cmd, container.Cmd...)
+	if container.resolved.cmd != nil {
+		cmd = append(cmd, container.resolved.cmd...)
+	}
+
+	if err := r.ensureNetwork(); err != nil {
+		return err
+	}
+	if err := r.ensureVolumes(container.resolved.binds); err != nil {
+		return err
 	}
 
-	r.context.logger.Info.Println("Creating container for", container.Name, "name", containerName, "env", env, "portSpecs", portSpecs, "cmd", cmd, "binds", container.Binds, "dns", container.DNSServer)
-	containerID, err := r.dockerClient.CreateContainer(containerName, container.Image, env, portSpecs, cmd, container.Binds, container.DNSServer)
+	r.context.logger.Info.Println("Creating container for", container.Name, "name", containerName, "env", env, "portSpecs", portSpecs, "cmd", cmd, "entrypoint", container.resolved.entrypoint, "binds", container.resolved.binds, "dns", container.resolved.dnsServer)
+	containerID, err := r.runtime.CreateContainer(ContainerConfig{
+		Name:         containerName,
+		Image:        r.context.rewriteImage(container.resolved.image),
+		Platform:     container.resolved.platform,
+		Env:          env,
+		PortSpecs:    portSpecs,
+		Cmd:          cmd,
+		Entrypoint:   container.resolved.entrypoint,
+		Binds:        container.resolved.binds,
+		DNSServer:    container.resolved.dnsServer,
+		Healthcheck:  container.resolved.healthcheck,
+		Labels:       r.getContainerLabels(container.Name),
+		Network:      r.context.network,
+		NetworkAlias: NormalizeName(container.Name),
+	})
 	if err != nil {
 		return err
 	}
-	container.containerID = containerID
+	container.setContainer(containerID, containerName)
 	return nil
 }
 
+// updateContainerIP provides the IP of the started container
+func (r *dockerLifecycleHandler) updateContainerIP(container *dockerContainer) error {
+	inspect, err := r.runtime.InspectContainer(container.containerID)
+	if err != nil {
+		return err
+	}
+	container.setIP(getContainerIP(inspect))
+	return nil
+}
+
+// getContainerIP provides the IP on the default bridge network or on the first network of the container
+func getContainerIP(inspect *types.ContainerJSON) string {
+	settings := inspect.NetworkSettings
+	if settings == nil {
+		return ""
+	}
+	if settings.IPAddress != "" {
+		return settings.IPAddress
+	}
+	names := make([]string, 0, len(settings.Networks))
+	for name := range settings.Networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if endpoint := settings.Networks[name]; endpoint != nil && endpoint.IPAddress != "" {
+			return endpoint.IPAddress
+		}
+	}
+	return ""
+}
+
 func (r *dockerLifecycleHandler) getContainerName(name string) string {
 	var containerName string
 	if r.context.ID != "" {
@@ -227,43 +902,161 @@ func (r *dockerLifecycleHandler) getContainerName(name string) string {
 	} else {
 		containerName = name
 	}
-	return normalizeName(containerName)
+	return NormalizeName(containerName)
+}
+
+// getContainerLabels identifies the containers created by this library, see Prune
+func (r *dockerLifecycleHandler) getContainerLabels(name string) map[string]string {
+	labels := r.getEnvironmentLabels()
+	labels[LabelComponent] = name
+	return labels
+}
+
+// getEnvironmentLabels identifies the networks and volumes created by this library, see Prune
+func (r *dockerLifecycleHandler) getEnvironmentLabels() map[string]string {
+	labels := make(map[string]string, len(r.context.labels)+2)
+	for k, v := range r.context.labels {
+		labels[k] = v
+	}
+	labels[LabelEnvironmentID] = r.context.ID
+	return labels
+}
+
+// ensureVolumes creates the named volumes of the binds, which do not exist. The volumes are labeled,
+// so that Prune can remove them, volumes created outside of this library are not labeled.
+func (r *dockerLifecycleHandler) ensureVolumes(binds []string) error {
+	r.volumesMutex.Lock()
+	defer r.volumesMutex.Unlock()
+	for _, bind := range binds {
+		name := strings.SplitN(bind, ":", 2)[0]
+		if !namedVolume.MatchString(name) {
+			continue
+		}
+		volume, err := r.runtime.GetVolumeByName(name)
+		if err != nil {
+			return err
+		}
+		if volume != nil {
+			continue
+		}
+		if err := r.runtime.CreateVolume(name, r.getEnvironmentLabels()); err != nil {
+			return err
+		}
+		r.context.logger.Info.Println("Created volume", name)
+	}
+	return nil
 }
 
-func (r *dockerLifecycleHandler) fetchLogs(containerID string, dstout, dsterr io.Writer) error {
-	reader, err := r.dockerClient.ContainerLogs(containerID, false)
+// ensureNetwork creates the network of the environment, if it does not exist
+func (r *dockerLifecycleHandler) ensureNetwork() error {
+	if r.context.network == "" {
+		return nil
+	}
+	r.networkMutex.Lock()
+	defer r.networkMutex.Unlock()
+	if r.networkID != "" {
+		return nil
+	}
+	network, err := r.runtime.GetNetworkByName(r.context.network)
+	if err != nil {
+		return err
+	}
+	if network != nil {
+		r.networkID = network.ID
+		return nil
+	}
+	networkID, err := r.runtime.CreateNetwork(r.context.network, r.getEnvironmentLabels())
 	if err != nil {
 		return err
 	}
+	r.context.logger.Info.Println("Created network", r.context.network, TruncateID(networkID))
+	r.networkID = networkID
+	r.networkCreated = true
+	return nil
+}
+
+// RemoveNetwork removes the network created by the environment
+func (r *dockerLifecycleHandler) RemoveNetwork() error {
+	r.networkMutex.Lock()
+	defer r.networkMutex.Unlock()
+	if !r.networkCreated {
+		return nil
+	}
+	r.context.logger.Info.Println("Remove network", r.context.network, TruncateID(r.networkID))
+	if err := r.runtime.RemoveNetwork(r.networkID); err != nil {
+		return err
+	}
+	r.networkID = ""
+	r.networkCreated = false
+	return nil
+}
+
+func (r *dockerLifecycleHandler) copyLogs(containerID string, timestamps bool, dstout, dsterr io.Writer) error {
+	reader, err := r.runtime.ContainerLogs(containerID, false, timestamps, time.Time{})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
 	_, err = stdcopy.StdCopy(dstout, dsterr, reader)
 	return err
 }
 
-func (r *dockerLifecycleHandler) followLogs(container *dockerContainer, dstout, dsterr io.Writer) error {
-	followClient, err := newDockerClient()
+func (r *dockerLifecycleHandler) fetchContainerLogs(container *dockerContainer) {
+	dstout, dsterr, err := container.getLogConsumer().Writers(container.Name)
 	if err != nil {
-		return err
+		r.context.logger.Error.Println("Log consumer error", err)
+		return
 	}
+	defer closeLogWriters(dstout, dsterr)
 
-	reader, err := followClient.ContainerLogs(container.containerID, true)
+	if err := r.copyLogs(container.containerID, container.LogTimestamps, dstout, dsterr); err != nil {
+		r.context.logger.Error.Println("Fetch logs error", err)
+	}
+}
+
+func (r *dockerLifecycleHandler) followLogs(container *dockerContainer) error {
+	dstout, dsterr, err := container.getLogConsumer().Writers(container.Name)
 	if err != nil {
 		return err
 	}
+	// after restart skip the output of the previous run, which was already followed
+	var since time.Time
+	if container.followedContainerID == container.containerID {
+		since = container.startedAt
+	}
+	reader, err := r.runtime.ContainerLogs(container.containerID, true, container.LogTimestamps, since)
+	if err != nil {
+		closeLogWriters(dstout, dsterr)
+		return err
+	}
+	container.followedContainerID = container.containerID
 	r.context.logger.Info.Println("Start follow logs", TruncateID(container.containerID))
+	var stopped int32
+	containerID := container.containerID
 	go func() {
-		defer followClient.Close()
 		for {
 			select {
 			case <-container.stopFollowLogsChannel:
-				r.context.logger.Info.Println("Received stop follow logs", TruncateID(container.containerID))
+				r.context.logger.Info.Println("Received stop follow logs", TruncateID(containerID))
+				atomic.StoreInt32(&stopped, 1)
+				reader.Close()
 				return
 			}
 		}
 
 	}()
 	go func() {
-		_, err = stdcopy.StdCopy(dstout, dsterr, reader)
-		if err != nil && err != io.EOF {
+		defer closeLogWriters(dstout, dsterr)
+
+		stdout, stderr := dstout, dsterr
+		if container.logBuffer != nil {
+			stdoutLines, stderrLines := container.logBuffer.writer(), container.logBuffer.writer()
+			defer stdoutLines.Flush()
+			defer stderrLines.Flush()
+			stdout, stderr = io.MultiWriter(dstout, stdoutLines), io.MultiWriter(dsterr, stderrLines)
+		}
+		_, err := stdcopy.StdCopy(stdout, stderr, reader)
+		if err != nil && err != io.EOF && atomic.LoadInt32(&stopped) == 0 {
 			r.context.logger.Error.Println("Follow logs error", err)
 		}
 	}()
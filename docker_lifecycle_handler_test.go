@@ -1,11 +1,18 @@
 package dockerit
 
 import (
+	"bytes"
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestDockerLifecycleHandler(t *testing.T) {
+	skipWithoutDaemon(t)
 	a := assert.New(t)
 
 	context, err := newDockerEnvironmentContext()
@@ -38,7 +45,7 @@ func TestDockerLifecycleHandler(t *testing.T) {
 	a.Nil(err)
 	a.Equal(containerID1, container.containerID)
 
-	err = handler.checkOrPullDockerImage(testImage, false)
+	err = handler.checkOrPullDockerImage(testImage, imageOptions{pullPolicy: PullNever})
 	a.Nil(err)
 
 	running, err := handler.isContainerRunning(container.containerID)
@@ -60,7 +67,10 @@ func TestDockerLifecycleHandler(t *testing.T) {
 	err = handler.Start(container)
 	a.Nil(err)
 
-	err = handler.fetchLogs(container.containerID, stdoutWriter(""), stdoutWriter(""))
+	var stdout, stderr bytes.Buffer
+	err = handler.WriteLogs(container, &stdout, &stderr)
+	a.Nil(err)
+	_, err = handler.Logs(container, time.Time{})
 	a.Nil(err)
 
 	err = handler.Stop(container)
@@ -87,10 +97,10 @@ func TestDockerLifecycleHandler(t *testing.T) {
 	a.False(exists)
 
 	// images should be deleted as RemoveImageAfterDestroy is set to true
-	err = handler.checkOrPullDockerImage(testImage, false)
+	err = handler.checkOrPullDockerImage(testImage, imageOptions{pullPolicy: PullNever})
 	a.EqualError(err, "Local images "+testImage+" does not exist")
 
-	err = handler.checkOrPullDockerImage(testImage, true)
+	err = handler.checkOrPullDockerImage(testImage, imageOptions{pullPolicy: PullAlways})
 	a.Nil(err)
 
 	err = handler.Destroy(container)
@@ -110,3 +120,112 @@ func TestDockerLifecycleHandler(t *testing.T) {
 	handler.Close()
 
 }
+
+func TestGetHostPorts(t *testing.T) {
+	a := assert.New(t)
+
+	hostPorts := getHostPorts(nat.PortMap{
+		"6379/tcp": {{HostIP: "10.0.0.1", HostPort: "32768"}},
+		"8080/tcp": {},
+	})
+	a.Equal(map[int]int{6379: 32768}, hostPorts)
+}
+
+func TestGetContainerIP(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("", getContainerIP(&types.ContainerJSON{}))
+	a.Equal("172.17.0.2", getContainerIP(&types.ContainerJSON{NetworkSettings: &types.NetworkSettings{
+		DefaultNetworkSettings: types.DefaultNetworkSettings{IPAddress: "172.17.0.2"},
+	}}))
+	a.Equal("172.18.0.3", getContainerIP(&types.ContainerJSON{NetworkSettings: &types.NetworkSettings{
+		Networks: map[string]*network.EndpointSettings{
+			"b-net": {IPAddress: "172.19.0.4"},
+			"a-net": {IPAddress: "172.18.0.3"},
+			"0-net": nil,
+		},
+	}}))
+}
+
+func TestHealthcheckWaitTimeout(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(4*time.Minute, (&Healthcheck{Test: []string{"CMD", "true"}}).waitTimeout())
+	a.Equal(10*time.Second+3*time.Second, (&Healthcheck{
+		Interval:    500 * time.Millisecond,
+		Timeout:     500 * time.Millisecond,
+		Retries:     2,
+		StartPeriod: 10 * time.Second,
+	}).waitTimeout())
+}
+
+func TestCallHooks(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	container, err := context.addContainer(DockerComponent{Name: "it-db", Image: "postgres"})
+	a.Nil(err)
+	container.containerID = "4a5f0e1c2b3d"
+	handler := &dockerLifecycleHandler{context: context}
+
+	calls := make([]string, 0)
+	hook := func(name string) Hook {
+		return HookFunc(func(componentName string, resolver ValueResolver, component Component) error {
+			a.Equal("it-db", componentName)
+			a.Equal("4a5f0e1c2b3d", component.ContainerID())
+			a.NotNil(resolver)
+			calls = append(calls, name)
+			if name == "fail" {
+				return errors.New("dump failed")
+			}
+			return nil
+		})
+	}
+	a.Nil(handler.callHooks(container, "BeforeStop", []Hook{hook("first"), hook("second")}))
+	a.Equal([]string{"first", "second"}, calls)
+
+	calls = calls[:0]
+	err = handler.callHooks(container, "BeforeStart", []Hook{hook("fail"), hook("skipped")})
+	a.EqualError(err, "DockerComponent [it-db] BeforeStart hook 0 failed: dump failed")
+	a.Equal([]string{"fail"}, calls)
+
+	calls = calls[:0]
+	err = handler.callAllHooks(container, "BeforeDestroy", []Hook{hook("fail"), hook("invoked"), hook("fail")})
+	a.EqualError(err, "DockerComponent [it-db] BeforeDestroy hook 0 failed: dump failed")
+	a.Equal([]string{"fail", "invoked", "fail"}, calls)
+}
+
+func TestNewContainerState(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(ContainerState{}, newContainerState(nil))
+	a.Equal(ContainerState{Status: "exited", ExitCode: 137, OOMKilled: true, Health: "unhealthy"}, newContainerState(&types.ContainerState{
+		Status:    "exited",
+		ExitCode:  137,
+		OOMKilled: true,
+		Health:    &types.Health{Status: "unhealthy"},
+	}))
+}
+
+func TestComponentHandle(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	container, err := context.addContainer(DockerComponent{Name: "it-db", Image: "postgres"})
+	a.Nil(err)
+	handler := &dockerLifecycleHandler{context: context}
+
+	var component Component = handler.newComponentHandle(container)
+	a.Equal("it-db", component.Name())
+	a.Equal("", component.ContainerID())
+	a.Equal(context.Host(), component.Context().Host())
+	_, err = component.Inspect()
+	a.EqualError(err, "DockerComponent [it-db] container was not created")
+	_, err = component.Logs(time.Time{})
+	a.EqualError(err, "DockerComponent [it-db] container was not created")
+
+	var callback Callback = ComponentCallbackFunc(func(component Component) error { return nil })
+	a.EqualError(callback.Call("it-db", context), "DockerComponent [it-db] callback requires the component container")
+}
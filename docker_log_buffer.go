@@ -0,0 +1,97 @@
+package dockerit
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogBufferSize = 1000
+)
+
+type logEntry struct {
+	time time.Time
+	line string
+}
+
+// logBuffer keeps the last lines of the container log output
+type logBuffer struct {
+	mu      sync.Mutex
+	entries []logEntry
+	start   int
+	count   int
+	// closed and replaced whenever a line is appended
+	appended chan struct{}
+}
+
+func newLogBuffer(size int) *logBuffer {
+	if size <= 0 {
+		size = defaultLogBufferSize
+	}
+	return &logBuffer{entries: make([]logEntry, size), appended: make(chan struct{})}
+}
+
+func (r *logBuffer) append(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry := logEntry{time: time.Now(), line: line}
+	if r.count < len(r.entries) {
+		r.entries[(r.start+r.count)%len(r.entries)] = entry
+		r.count++
+	} else {
+		r.entries[r.start] = entry
+		r.start = (r.start + 1) % len(r.entries)
+	}
+	close(r.appended)
+	r.appended = make(chan struct{})
+}
+
+// lines returns buffered lines appended not before since and a channel closed on the next append
+func (r *logBuffer) lines(since time.Time) ([]string, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]string, 0)
+	for i := 0; i < r.count; i++ {
+		entry := r.entries[(r.start+i)%len(r.entries)]
+		if entry.time.Before(since) {
+			continue
+		}
+		result = append(result, entry.line)
+	}
+	return result, r.appended
+}
+
+// writer provides an io.Writer splitting the written output into lines
+func (r *logBuffer) writer() *logLineWriter {
+	return &logLineWriter{buffer: r}
+}
+
+type logLineWriter struct {
+	buffer  *logBuffer
+	partial bytes.Buffer
+}
+
+// implements io.Writer interface
+func (w *logLineWriter) Write(b []byte) (int, error) {
+	w.partial.Write(b)
+	for {
+		i := bytes.IndexByte(w.partial.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := w.partial.Next(i + 1)
+		w.buffer.append(string(bytes.TrimRight(line, "\r\n")))
+	}
+	return len(b), nil
+}
+
+// Flush appends the last line not terminated by new line
+func (w *logLineWriter) Flush() {
+	if w.partial.Len() > 0 {
+		w.buffer.append(string(bytes.TrimRight(w.partial.Bytes(), "\r\n")))
+		w.partial.Reset()
+	}
+}
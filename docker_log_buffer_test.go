@@ -0,0 +1,124 @@
+package dockerit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestLogBufferKeepsLastLines(t *testing.T) {
+	a := assert.New(t)
+
+	buffer := newLogBuffer(3)
+	for _, line := range []string{"1", "2", "3", "4", "5"} {
+		buffer.append(line)
+	}
+	lines, _ := buffer.lines(time.Time{})
+	a.Equal([]string{"3", "4", "5"}, lines)
+}
+
+func TestLogBufferDefaultSize(t *testing.T) {
+	a := assert.New(t)
+
+	buffer := newLogBuffer(0)
+	a.Len(buffer.entries, defaultLogBufferSize)
+}
+
+func TestLogBufferLinesSince(t *testing.T) {
+	a := assert.New(t)
+
+	buffer := newLogBuffer(10)
+	buffer.append("before")
+	since := time.Now()
+	time.Sleep(time.Millisecond)
+	buffer.append("after")
+
+	lines, _ := buffer.lines(since)
+	a.Equal([]string{"after"}, lines)
+}
+
+func TestLogBufferNotifiesAppend(t *testing.T) {
+	a := assert.New(t)
+
+	buffer := newLogBuffer(10)
+	_, appended := buffer.lines(time.Time{})
+	buffer.append("line")
+
+	select {
+	case <-appended:
+	case <-time.After(time.Second):
+		a.Fail("append was not notified")
+	}
+}
+
+func TestLogLineWriterSplitsLines(t *testing.T) {
+	a := assert.New(t)
+
+	buffer := newLogBuffer(10)
+	writer := buffer.writer()
+	io.WriteString(writer, "first\nsec")
+	io.WriteString(writer, "ond\r\nthi")
+
+	lines, _ := buffer.lines(time.Time{})
+	a.Equal([]string{"first", "second"}, lines)
+
+	writer.Flush()
+	lines, _ = buffer.lines(time.Time{})
+	a.Equal([]string{"first", "second", "thi"}, lines)
+}
+
+func TestDockerEnvironmentLogs(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	container, err := context.addContainer(DockerComponent{Name: "it-redis", Image: "redis", FollowLogs: true})
+	a.Nil(err)
+	_, err = context.addContainer(DockerComponent{Name: "it-kafka", Image: "kafka"})
+	a.Nil(err)
+	env := &DockerEnvironment{context: context}
+
+	container.logBuffer.append("Server initialized")
+	container.logBuffer.append("WARNING overcommit_memory is set to 0")
+
+	lines, err := env.Logs("it-redis", time.Time{})
+	a.Nil(err)
+	a.Equal([]string{"Server initialized", "WARNING overcommit_memory is set to 0"}, lines)
+
+	found, err := env.LogsContain("it-redis", "^WARNING .* set to \\d$")
+	a.Nil(err)
+	a.True(found)
+
+	found, err = env.LogsContain("it-redis", "Ready to accept connections")
+	a.Nil(err)
+	a.False(found)
+
+	_, err = env.LogsContain("it-redis", "(")
+	a.NotNil(err)
+
+	_, err = env.Logs("it-kafka", time.Time{})
+	a.EqualError(err, "DockerComponent [it-kafka] does not follow logs")
+
+	_, err = env.Logs("it-unknown", time.Time{})
+	a.EqualError(err, "DockerComponent [it-unknown] is not configured")
+}
+
+func TestDockerEnvironmentWaitForLog(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	container, err := context.addContainer(DockerComponent{Name: "it-redis", Image: "redis", FollowLogs: true})
+	a.Nil(err)
+	env := &DockerEnvironment{context: context}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		container.logBuffer.append("Ready to accept connections")
+	}()
+	a.Nil(env.WaitForLog("it-redis", "Ready to accept", time.Second))
+
+	err = env.WaitForLog("it-redis", "shutdown", 50*time.Millisecond)
+	a.EqualError(err, "Log output of 'it-redis' did not match 'shutdown' within 50ms")
+}
@@ -0,0 +1,177 @@
+package dockerit
+
+import (
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// LabelEnvironmentID is the label holding the environment ID of containers, networks and volumes created by this library
+	LabelEnvironmentID = "dockerit.environment"
+	// LabelComponent is the label holding the component name of a container
+	LabelComponent = "dockerit.component"
+)
+
+// containers created before the labels were introduced are named <component>-<random environment ID>.
+// The name is matched only for stopped containers and only with PruneFilter.MatchNames, as it does not prove
+// that the container was created by this library.
+var generatedContainerName = regexp.MustCompile(`^/?(.+)-([0-9a-f]{12})$`)
+
+// PruneFilter selects the docker resources removed by Prune
+type PruneFilter struct {
+	// Environment ID. If empty, resources of all environments are selected.
+	EnvironmentID string
+	// Component name. If empty, resources of all components are selected. Networks and volumes do not belong to a component.
+	ComponentName string
+	// Minimal age of the resources
+	OlderThan time.Duration
+	// List the selected resources without removing them
+	DryRun bool
+	// Select also the stopped containers without labels named <component>-<environment ID>, which were created
+	// by older versions of this library. Other containers can match the names, list them with DryRun first.
+	MatchNames bool
+}
+
+// PrunedResource describes a docker resource selected by Prune
+type PrunedResource struct {
+	// Type of the resource: container, network or volume
+	Type string
+	// Resource ID, the name of volumes
+	ID string
+	// Resource name
+	Name string
+	// Environment ID of the resource
+	EnvironmentID string
+	// Component name of a container
+	ComponentName string
+	// Creation time
+	Created time.Time
+}
+
+// Prune removes containers, networks and named volumes created by this library, e.g. left over by a crashed test run.
+// The resources are identified by labels, see PruneFilter.MatchNames for the containers of older versions.
+// Named volumes which existed before the environment started are not labeled and not removed.
+// The selected resources are returned.
+func Prune(filter PruneFilter) ([]PrunedResource, error) {
+	return PruneWithClientConfig(ClientConfig{}, filter)
+}
+
+// PruneWithClientConfig removes the resources created by this library like Prune, using the docker client configuration
+func PruneWithClientConfig(config ClientConfig, filter PruneFilter) ([]PrunedResource, error) {
+	dockerClient, err := newDockerClient(config)
+	if err != nil {
+		return nil, err
+	}
+	defer dockerClient.Close()
+
+	now := time.Now()
+	resources := make([]PrunedResource, 0)
+
+	containers, err := dockerClient.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	// volumes of the containers, which are not removed, are kept
+	usedVolumes := make(map[string]struct{})
+	for _, container := range containers {
+		name := ""
+		if len(container.Names) != 0 {
+			name = strings.TrimPrefix(container.Names[0], "/")
+		}
+		resource, ok := newContainerResource(container.ID, name, container.Labels, container.State == "running" || !filter.MatchNames, time.Unix(container.Created, 0))
+		if ok && filter.matches(resource, now) {
+			resources = append(resources, resource)
+			continue
+		}
+		for _, mount := range container.Mounts {
+			if mount.Type == "volume" {
+				usedVolumes[mount.Name] = struct{}{}
+			}
+		}
+	}
+
+	if filter.ComponentName == "" {
+		networks, err := dockerClient.ListNetworks(LabelEnvironmentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, network := range networks {
+			resource := PrunedResource{Type: "network", ID: network.ID, Name: network.Name, EnvironmentID: network.Labels[LabelEnvironmentID], Created: network.Created}
+			if filter.matches(resource, now) {
+				resources = append(resources, resource)
+			}
+		}
+		volumes, err := dockerClient.ListVolumes(LabelEnvironmentID)
+		if err != nil {
+			return nil, err
+		}
+		for _, volume := range volumes {
+			if _, used := usedVolumes[volume.Name]; used {
+				continue
+			}
+			if resource := newVolumeResource(volume); filter.matches(resource, now) {
+				resources = append(resources, resource)
+			}
+		}
+	}
+
+	if filter.DryRun {
+		return resources, nil
+	}
+	// containers are removed first, they can use the networks and volumes
+	for _, resource := range resources {
+		switch resource.Type {
+		case "container":
+			err = dockerClient.RemoveContainer(resource.ID)
+		case "network":
+			err = dockerClient.RemoveNetwork(resource.ID)
+		case "volume":
+			err = dockerClient.RemoveVolume(resource.ID)
+		}
+		if err != nil {
+			return resources, fmt.Errorf("Remove %s %s failed: %v", resource.Type, resource.Name, err)
+		}
+	}
+	return resources, nil
+}
+
+// newContainerResource identifies a container created by this library, the name is not matched if skipName is set
+func newContainerResource(id string, name string, labels map[string]string, skipName bool, created time.Time) (PrunedResource, bool) {
+	resource := PrunedResource{Type: "container", ID: id, Name: name, Created: created}
+	if environmentID, ok := labels[LabelEnvironmentID]; ok {
+		resource.EnvironmentID = environmentID
+		resource.ComponentName = labels[LabelComponent]
+		return resource, true
+	}
+	if skipName {
+		return resource, false
+	}
+	if match := generatedContainerName.FindStringSubmatch(name); match != nil {
+		resource.ComponentName = match[1]
+		resource.EnvironmentID = match[2]
+		return resource, true
+	}
+	return resource, false
+}
+
+// newVolumeResource describes a labeled volume, the creation time is zero if the driver does not provide it
+func newVolumeResource(volume *types.Volume) PrunedResource {
+	created, _ := time.Parse(time.RFC3339, volume.CreatedAt)
+	return PrunedResource{Type: "volume", ID: volume.Name, Name: volume.Name, EnvironmentID: volume.Labels[LabelEnvironmentID], Created: created}
+}
+
+func (r PruneFilter) matches(resource PrunedResource, now time.Time) bool {
+	if r.EnvironmentID != "" && r.EnvironmentID != resource.EnvironmentID {
+		return false
+	}
+	if r.ComponentName != "" && NormalizeName(r.ComponentName) != NormalizeName(resource.ComponentName) {
+		return false
+	}
+	if r.OlderThan != 0 && (resource.Created.IsZero() || now.Sub(resource.Created) < r.OlderThan) {
+		return false
+	}
+	return true
+}
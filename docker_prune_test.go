@@ -0,0 +1,56 @@
+package dockerit
+
+import (
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewContainerResource(t *testing.T) {
+	a := assert.New(t)
+	created := time.Now()
+
+	resource, ok := newContainerResource("id1", "it-redis-my-env", map[string]string{LabelEnvironmentID: "my-env", LabelComponent: "it-redis"}, true, created)
+	a.True(ok)
+	a.Equal(PrunedResource{Type: "container", ID: "id1", Name: "it-redis-my-env", EnvironmentID: "my-env", ComponentName: "it-redis", Created: created}, resource)
+
+	resource, ok = newContainerResource("id2", "it-redis-0123456789ab", nil, false, created)
+	a.True(ok)
+	a.Equal("0123456789ab", resource.EnvironmentID)
+	a.Equal("it-redis", resource.ComponentName)
+
+	// the name is not matched for running containers or without PruneFilter.MatchNames
+	_, ok = newContainerResource("id2", "it-redis-0123456789ab", nil, true, created)
+	a.False(ok)
+
+	_, ok = newContainerResource("id3", "my-postgres", nil, false, created)
+	a.False(ok)
+}
+
+func TestPruneFilterMatches(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	resource := PrunedResource{Type: "container", EnvironmentID: "0123456789ab", ComponentName: "it-redis", Created: now.Add(-2 * time.Hour)}
+
+	a.True(PruneFilter{}.matches(resource, now))
+	a.True(PruneFilter{EnvironmentID: "0123456789ab", ComponentName: "IT-Redis", OlderThan: time.Hour}.matches(resource, now))
+	a.False(PruneFilter{EnvironmentID: "ba9876543210"}.matches(resource, now))
+	a.False(PruneFilter{ComponentName: "it-kafka"}.matches(resource, now))
+	a.False(PruneFilter{OlderThan: 3 * time.Hour}.matches(resource, now))
+}
+
+func TestNewVolumeResource(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+
+	resource := newVolumeResource(&types.Volume{Name: "project_data", CreatedAt: "2018-01-02T03:04:05Z", Labels: map[string]string{LabelEnvironmentID: "my-env"}})
+	a.Equal(PrunedResource{Type: "volume", ID: "project_data", Name: "project_data", EnvironmentID: "my-env", Created: time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)}, resource)
+	a.True(PruneFilter{OlderThan: time.Hour}.matches(resource, now))
+
+	// the age of volumes without creation time is unknown
+	resource = newVolumeResource(&types.Volume{Name: "data", Labels: map[string]string{LabelEnvironmentID: "my-env"}})
+	a.True(resource.Created.IsZero())
+	a.True(PruneFilter{EnvironmentID: "my-env"}.matches(resource, now))
+	a.False(PruneFilter{OlderThan: time.Hour}.matches(resource, now))
+}
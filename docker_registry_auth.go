@@ -0,0 +1,236 @@
+package dockerit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultRegistryHostname = "docker.io"
+	// key of the docker hub credentials in docker config and credential helpers
+	defaultRegistryServerAddress = "https://index.docker.io/v1/"
+	credentialHelperPrefix       = "docker-credential-"
+	credentialHelperIdentityUser = "<token>"
+)
+
+// RegistryAuth holds credentials used to pull images from a private registry
+type RegistryAuth struct {
+	Username string
+	Password string
+	// Identity token used instead of username and password
+	IdentityToken string
+}
+
+type dockerConfigFile struct {
+	Auths       map[string]dockerConfigAuth `json:"auths"`
+	CredsStore  string                      `json:"credsStore,omitempty"`
+	CredHelpers map[string]string           `json:"credHelpers,omitempty"`
+	// name of the docker CLI context in use
+	CurrentContext string `json:"currentContext,omitempty"`
+}
+
+type dockerConfigAuth struct {
+	Auth          string `json:"auth,omitempty"`
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+type credentialHelperOutput struct {
+	ServerURL string
+	Username  string
+	Secret    string
+}
+
+// getDockerConfigDir provides the docker CLI config directory, $DOCKER_CONFIG or ~/.docker
+func getDockerConfigDir() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return dir
+	}
+	if home := os.Getenv("HOME"); home != "" {
+		return filepath.Join(home, ".docker")
+	}
+	return ""
+}
+
+func loadDockerConfigFile(dir string) (*dockerConfigFile, error) {
+	config := &dockerConfigFile{}
+	if dir == "" {
+		return config, nil
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return config, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("Docker config %s is invalid: %v", filepath.Join(dir, "config.json"), err)
+	}
+	return config, nil
+}
+
+// getRegistryHostname provides the registry hostname of an image reference
+func getRegistryHostname(image string) string {
+	i := strings.IndexRune(image, '/')
+	if i < 0 {
+		return defaultRegistryHostname
+	}
+	hostname := image[:i]
+	if !strings.ContainsAny(hostname, ".:") && hostname != "localhost" {
+		return defaultRegistryHostname
+	}
+	if hostname == "index.docker.io" || hostname == "registry-1.docker.io" {
+		return defaultRegistryHostname
+	}
+	return hostname
+}
+
+// normalizeRegistryAddress strips scheme and path from the registry address
+func normalizeRegistryAddress(address string) string {
+	address = strings.TrimPrefix(address, "http://")
+	address = strings.TrimPrefix(address, "https://")
+	if i := strings.IndexRune(address, '/'); i >= 0 {
+		address = address[:i]
+	}
+	if address == "index.docker.io" || address == "registry-1.docker.io" {
+		return defaultRegistryHostname
+	}
+	return address
+}
+
+// lookupRegistryAuth finds credentials for the registry hostname in the docker config and credential helpers.
+// It returns nil, if no credentials were found. A failure of the credentials store is logged and the auths
+// of the docker config are used, so that public images can be pulled with a config copied from another machine.
+func (r *dockerConfigFile) lookupRegistryAuth(hostname string, logger *logger) (*RegistryAuth, error) {
+	serverAddress := hostname
+	if hostname == defaultRegistryHostname {
+		serverAddress = defaultRegistryServerAddress
+	}
+	if helper, ok := r.CredHelpers[hostname]; ok {
+		return getCredentialHelperAuth(helper, serverAddress)
+	}
+	if r.CredsStore != "" {
+		auth, err := getCredentialHelperAuth(r.CredsStore, serverAddress)
+		if err != nil {
+			logger.Error.Println("Credentials store error, using the auths of the docker config:", err)
+		} else if auth != nil {
+			return auth, nil
+		}
+	}
+	address, ok := r.findAuthAddress(hostname, serverAddress)
+	if !ok {
+		return nil, nil
+	}
+	configAuth := r.Auths[address]
+	auth := &RegistryAuth{
+		Username:      configAuth.Username,
+		Password:      configAuth.Password,
+		IdentityToken: configAuth.IdentityToken,
+	}
+	if configAuth.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(configAuth.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("Docker config auth for %s is invalid: %v", address, err)
+		}
+		pair := strings.SplitN(string(decoded), ":", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("Docker config auth for %s is invalid", address)
+		}
+		auth.Username, auth.Password = pair[0], pair[1]
+	}
+	return auth, nil
+}
+
+// findAuthAddress provides the auths key of the registry. An exact match is preferred,
+// otherwise the first of the sorted addresses normalized to the hostname is used.
+func (r *dockerConfigFile) findAuthAddress(hostname string, serverAddress string) (string, bool) {
+	for _, address := range []string{hostname, serverAddress} {
+		if _, ok := r.Auths[address]; ok {
+			return address, true
+		}
+	}
+	addresses := make([]string, 0, len(r.Auths))
+	for address := range r.Auths {
+		if normalizeRegistryAddress(address) == hostname {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) == 0 {
+		return "", false
+	}
+	sort.Strings(addresses)
+	return addresses[0], true
+}
+
+func getCredentialHelperAuth(helper string, serverAddress string) (*RegistryAuth, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(credentialHelperPrefix+helper, "get")
+	cmd.Stdin = strings.NewReader(serverAddress)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stdout.String() + stderr.String())
+		if strings.Contains(message, "credentials not found") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Credential helper %s%s failed: %v %s", credentialHelperPrefix, helper, err, message)
+	}
+	output := credentialHelperOutput{}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("Credential helper %s%s output is invalid: %v", credentialHelperPrefix, helper, err)
+	}
+	if output.Username == credentialHelperIdentityUser {
+		return &RegistryAuth{IdentityToken: output.Secret}, nil
+	}
+	return &RegistryAuth{Username: output.Username, Password: output.Secret}, nil
+}
+
+// encodeRegistryAuth provides the base64 encoded auth config expected by the image pull API
+func encodeRegistryAuth(hostname string, auth *RegistryAuth) (string, error) {
+	if auth == nil {
+		return "", nil
+	}
+	serverAddress := hostname
+	if hostname == defaultRegistryHostname {
+		serverAddress = defaultRegistryServerAddress
+	}
+	authConfig := types.AuthConfig{
+		Username:      auth.Username,
+		Password:      auth.Password,
+		IdentityToken: auth.IdentityToken,
+		ServerAddress: serverAddress,
+	}
+	data, err := json.Marshal(authConfig)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// getImageRegistryAuth provides the encoded credentials for an image. Explicit credentials take precedence
+// over the docker config.
+func getImageRegistryAuth(image string, explicit *RegistryAuth, logger *logger) (string, error) {
+	hostname := getRegistryHostname(image)
+	auth := explicit
+	if auth == nil {
+		config, err := loadDockerConfigFile(getDockerConfigDir())
+		if err != nil {
+			return "", err
+		}
+		if auth, err = config.lookupRegistryAuth(hostname, logger); err != nil {
+			return "", err
+		}
+	}
+	return encodeRegistryAuth(hostname, auth)
+}
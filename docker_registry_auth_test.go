@@ -0,0 +1,153 @@
+package dockerit
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetRegistryHostname(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("docker.io", getRegistryHostname("redis"))
+	a.Equal("docker.io", getRegistryHostname("postgres:9.6"))
+	a.Equal("docker.io", getRegistryHostname("spotify/kafka"))
+	a.Equal("docker.io", getRegistryHostname("index.docker.io/library/redis"))
+	a.Equal("docker.elastic.co", getRegistryHostname("docker.elastic.co/elasticsearch/elasticsearch:5.5.0"))
+	a.Equal("localhost:5000", getRegistryHostname("localhost:5000/my-app"))
+	a.Equal("localhost", getRegistryHostname("localhost/my-app"))
+}
+
+func TestNormalizeRegistryAddress(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("docker.io", normalizeRegistryAddress("https://index.docker.io/v1/"))
+	a.Equal("registry.example.com", normalizeRegistryAddress("https://registry.example.com/v2/"))
+	a.Equal("registry.example.com:5000", normalizeRegistryAddress("registry.example.com:5000"))
+}
+
+func TestLookupRegistryAuthFromDockerConfig(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "docker-it-config")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(`{
+		"auths": {
+			"https://index.docker.io/v1/": {"auth": "`+base64.StdEncoding.EncodeToString([]byte("hub-user:hub:secret"))+`"},
+			"registry.example.com": {"identitytoken": "my-token"}
+		}
+	}`), 0644)
+	a.Nil(err)
+
+	config, err := loadDockerConfigFile(dir)
+	a.Nil(err)
+
+	auth, err := config.lookupRegistryAuth("docker.io", newWriterLogger(ioutil.Discard))
+	a.Nil(err)
+	a.Equal(&RegistryAuth{Username: "hub-user", Password: "hub:secret"}, auth)
+
+	auth, err = config.lookupRegistryAuth("registry.example.com", newWriterLogger(ioutil.Discard))
+	a.Nil(err)
+	a.Equal(&RegistryAuth{IdentityToken: "my-token"}, auth)
+
+	auth, err = config.lookupRegistryAuth("quay.io", newWriterLogger(ioutil.Discard))
+	a.Nil(err)
+	a.Nil(auth)
+}
+
+func TestLoadMissingDockerConfig(t *testing.T) {
+	a := assert.New(t)
+
+	config, err := loadDockerConfigFile(filepath.Join(os.TempDir(), "docker-it-does-not-exist"))
+	a.Nil(err)
+	auth, err := config.lookupRegistryAuth("docker.io", newWriterLogger(ioutil.Discard))
+	a.Nil(err)
+	a.Nil(auth)
+}
+
+func TestLookupRegistryAuthFromCredentialHelper(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "docker-it-helper")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+
+	script := `#!/bin/sh
+read server
+if [ "$server" = "gcr.io" ]; then
+  echo '{"ServerURL":"gcr.io","Username":"_json_key","Secret":"gcr-secret"}'
+  exit 0
+fi
+echo "credentials not found in native keychain"
+exit 1
+`
+	err = ioutil.WriteFile(filepath.Join(dir, "docker-credential-fake"), []byte(script), 0755)
+	a.Nil(err)
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	config := &dockerConfigFile{CredHelpers: map[string]string{"gcr.io": "fake"}, CredsStore: "fake"}
+
+	auth, err := config.lookupRegistryAuth("gcr.io", newWriterLogger(ioutil.Discard))
+	a.Nil(err)
+	a.Equal(&RegistryAuth{Username: "_json_key", Password: "gcr-secret"}, auth)
+
+	auth, err = config.lookupRegistryAuth("docker.io", newWriterLogger(ioutil.Discard))
+	a.Nil(err)
+	a.Nil(auth)
+}
+
+func TestLookupRegistryAuthCredentialsStoreFailure(t *testing.T) {
+	a := assert.New(t)
+
+	config := &dockerConfigFile{
+		CredsStore: "docker-it-does-not-exist",
+		Auths: map[string]dockerConfigAuth{
+			"https://registry.example.com/v2/": {IdentityToken: "v2-token"},
+			"https://registry.example.com/v1/": {IdentityToken: "v1-token"},
+			"quay.io":                          {IdentityToken: "quay-token"},
+			"https://quay.io/v1/":              {IdentityToken: "other-token"},
+		},
+	}
+	var output bytes.Buffer
+	auth, err := config.lookupRegistryAuth("registry.example.com", newWriterLogger(&output))
+	a.Nil(err)
+	// the first of the sorted addresses
+	a.Equal(&RegistryAuth{IdentityToken: "v1-token"}, auth)
+	a.Contains(output.String(), "Credentials store error")
+
+	// the exact address is preferred
+	auth, err = config.lookupRegistryAuth("quay.io", newWriterLogger(&output))
+	a.Nil(err)
+	a.Equal(&RegistryAuth{IdentityToken: "quay-token"}, auth)
+
+	// anonymous pull
+	auth, err = config.lookupRegistryAuth("docker.io", newWriterLogger(&output))
+	a.Nil(err)
+	a.Nil(auth)
+}
+
+func TestEncodeRegistryAuth(t *testing.T) {
+	a := assert.New(t)
+
+	encoded, err := encodeRegistryAuth("docker.io", nil)
+	a.Nil(err)
+	a.Empty(encoded)
+
+	encoded, err = encodeRegistryAuth("docker.io", &RegistryAuth{Username: "user", Password: "secret"})
+	a.Nil(err)
+	data, err := base64.URLEncoding.DecodeString(encoded)
+	a.Nil(err)
+	authConfig := types.AuthConfig{}
+	a.Nil(json.Unmarshal(data, &authConfig))
+	a.Equal(types.AuthConfig{Username: "user", Password: "secret", ServerAddress: "https://index.docker.io/v1/"}, authConfig)
+}
@@ -0,0 +1,158 @@
+package dockerit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Phase is a lifecycle phase of a docker component
+type Phase string
+
+const (
+	// PhasePull checks, loads or pulls the image
+	PhasePull Phase = "pull"
+	// PhaseCreate creates the container
+	PhaseCreate Phase = "create"
+	// PhaseStart starts the container
+	PhaseStart Phase = "start"
+	// PhaseWait waits for the healthy container and invokes the AfterStart callback
+	PhaseWait Phase = "wait"
+	// PhaseStop stops the container
+	PhaseStop Phase = "stop"
+	// PhaseRemove removes the container
+	PhaseRemove Phase = "remove"
+)
+
+// reportPhases are the phases in the order of the lifecycle
+var reportPhases = []Phase{PhasePull, PhaseCreate, PhaseStart, PhaseWait, PhaseStop, PhaseRemove}
+
+// Report holds the durations of the lifecycle phases of the components
+type Report struct {
+	Components []ComponentReport
+}
+
+// ComponentReport holds the durations of the lifecycle phases of a component.
+// The durations of a phase executed several times, e.g. after restart, are summed up.
+type ComponentReport struct {
+	// Name of the docker component
+	Name string
+	// Durations of the executed phases
+	Durations map[Phase]time.Duration
+}
+
+// Total returns the sum of the phase durations
+func (r ComponentReport) Total() time.Duration {
+	var total time.Duration
+	for _, duration := range r.Durations {
+		total += duration
+	}
+	return total
+}
+
+// WriteText writes the report as a table with a row per component
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := []string{"COMPONENT"}
+	for _, phase := range reportPhases {
+		header = append(header, strings.ToUpper(string(phase)))
+	}
+	header = append(header, "TOTAL")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, component := range r.Components {
+		row := []string{component.Name}
+		for _, phase := range reportPhases {
+			row = append(row, formatPhaseDuration(component.Durations[phase]))
+		}
+		row = append(row, formatPhaseDuration(component.Total()))
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func formatPhaseDuration(duration time.Duration) string {
+	if duration == 0 {
+		return "-"
+	}
+	return duration.Round(time.Millisecond).String()
+}
+
+type jsonComponentReport struct {
+	Name      string            `json:"name"`
+	Durations map[Phase]float64 `json:"durations"`
+	Total     float64           `json:"total"`
+}
+
+// WriteJSON writes the report as JSON with the durations in seconds
+func (r *Report) WriteJSON(w io.Writer) error {
+	components := make([]jsonComponentReport, 0, len(r.Components))
+	for _, component := range r.Components {
+		durations := make(map[Phase]float64, len(reportPhases))
+		for _, phase := range reportPhases {
+			durations[phase] = component.Durations[phase].Seconds()
+		}
+		components = append(components, jsonComponentReport{Name: component.Name, Durations: durations, Total: component.Total().Seconds()})
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{"components": components}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Report provides the durations of the lifecycle phases of all components
+func (r *DockerEnvironment) Report() *Report {
+	return newReport(r.getContainers())
+}
+
+func newReport(containers []*dockerContainer) *Report {
+	report := &Report{Components: make([]ComponentReport, 0, len(containers))}
+	for _, container := range containers {
+		report.Components = append(report.Components, ComponentReport{Name: container.Name, Durations: container.phaseDurations.get()})
+	}
+	return report
+}
+
+// logReport logs the report of the containers as table
+func (r *DockerEnvironment) logReport(containers []*dockerContainer) {
+	var b bytes.Buffer
+	if err := newReport(containers).WriteText(&b); err != nil {
+		r.context.logger.Error.Println("Report error", err)
+		return
+	}
+	for _, line := range strings.Split(strings.TrimRight(b.String(), "\n"), "\n") {
+		r.context.logger.Info.Println(line)
+	}
+}
+
+// phaseDurations records the durations of the lifecycle phases of a container
+type phaseDurations struct {
+	mutex     sync.Mutex
+	durations map[Phase]time.Duration
+}
+
+// record adds the duration since start to the phase
+func (r *phaseDurations) record(phase Phase, start time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.durations == nil {
+		r.durations = make(map[Phase]time.Duration)
+	}
+	r.durations[phase] += time.Since(start)
+}
+
+func (r *phaseDurations) get() map[Phase]time.Duration {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	result := make(map[Phase]time.Duration, len(r.durations))
+	for phase, duration := range r.durations {
+		result[phase] = duration
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package dockerit
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	return &Report{Components: []ComponentReport{
+		{Name: "it-es", Durations: map[Phase]time.Duration{PhasePull: 2 * time.Second, PhaseStart: 1500 * time.Millisecond, PhaseWait: 8 * time.Second}},
+		{Name: "it-redis", Durations: map[Phase]time.Duration{PhaseCreate: 250 * time.Millisecond}},
+	}}
+}
+
+func TestReportWriteText(t *testing.T) {
+	a := assert.New(t)
+
+	var b bytes.Buffer
+	a.Nil(testReport().WriteText(&b))
+	a.Equal(""+
+		"COMPONENT  PULL  CREATE  START  WAIT  STOP  REMOVE  TOTAL\n"+
+		"it-es      2s    -       1.5s   8s    -     -       11.5s\n"+
+		"it-redis   -     250ms   -      -     -     -       250ms\n", b.String())
+}
+
+func TestReportWriteJSON(t *testing.T) {
+	a := assert.New(t)
+
+	var b bytes.Buffer
+	a.Nil(testReport().WriteJSON(&b))
+	a.JSONEq(`{"components": [
+		{"name": "it-es", "durations": {"pull": 2, "create": 0, "start": 1.5, "wait": 8, "stop": 0, "remove": 0}, "total": 11.5},
+		{"name": "it-redis", "durations": {"pull": 0, "create": 0.25, "start": 0, "wait": 0, "stop": 0, "remove": 0}, "total": 0.25}
+	]}`, b.String())
+}
+
+func TestPhaseDurations(t *testing.T) {
+	a := assert.New(t)
+
+	context, err := newDockerEnvironmentContext()
+	a.Nil(err)
+	container, err := context.addContainer(DockerComponent{Name: "it-redis", Image: "redis"})
+	a.Nil(err)
+
+	container.phaseDurations.record(PhaseStart, time.Now().Add(-time.Second))
+	container.phaseDurations.record(PhaseStart, time.Now().Add(-time.Second))
+	report := newReport([]*dockerContainer{container})
+	a.Len(report.Components, 1)
+	a.Equal("it-redis", report.Components[0].Name)
+	a.True(report.Components[0].Durations[PhaseStart] >= 2*time.Second)
+	a.Equal(report.Components[0].Durations[PhaseStart], report.Components[0].Total())
+}
@@ -0,0 +1,100 @@
+package dockerit
+
+import (
+	"context"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"io"
+	"log"
+	"time"
+)
+
+// Runtime runs the containers of a docker environment. It is implemented by the docker client,
+// the dockerittest package provides an in-memory implementation for unit tests without a docker daemon.
+type Runtime interface {
+	// GetImageByName returns the image with the given name, nil if it does not exist
+	GetImageByName(imageName string) (*types.ImageSummary, error)
+	// InspectImage returns the low-level information of an image
+	InspectImage(imageName string) (*types.ImageInspect, error)
+	// PullImage pulls an image of the platform os[/arch[/variant]] from a registry, the platform of the docker host
+	// if it is empty. The registryAuth is the base64 encoded auth config.
+	PullImage(imageName string, platform string, registryAuth string, logger *log.Logger) error
+	// LoadImage loads images from a tar archive created by docker save
+	LoadImage(archive io.Reader) error
+	// SaveImages writes a tar archive of the images
+	SaveImages(imageNames []string, w io.Writer) error
+	// TagImage adds the target name to the source image
+	TagImage(source string, target string) error
+	// RemoveImageByName removes all images with the given name
+	RemoveImageByName(imageName string) error
+
+	// GetContainerByID returns the container with the given ID, nil if it does not exist
+	GetContainerByID(containerID string) (*types.Container, error)
+	// GetContainerByName returns the container with the given name, nil if it does not exist
+	GetContainerByName(containerName string) (*types.Container, error)
+	// CreateContainer creates a container and returns its ID
+	CreateContainer(config ContainerConfig) (string, error)
+	// StartContainer starts a container
+	StartContainer(containerID string) error
+	// StopContainer stops a container, which is killed after the timeout. If the timeout is nil, the default is used.
+	StopContainer(containerID string, timeout *time.Duration) error
+	// RemoveContainer kills and removes a container
+	RemoveContainer(containerID string) error
+	// InspectContainer returns the low-level information of a container
+	InspectContainer(containerID string) (*types.ContainerJSON, error)
+	// ContainerLogs returns the log output multiplexed as by the docker API, see stdcopy.StdCopy.
+	// Logs created before since are skipped, unless since is zero.
+	ContainerLogs(containerID string, follow bool, timestamps bool, since time.Time) (io.ReadCloser, error)
+	// ExecContainer runs a command in a running container and returns its exit code
+	ExecContainer(containerID string, cmd []string, stdout io.Writer, stderr io.Writer) (int, error)
+	// Events returns the events of the containers having the label key=value until the context is done
+	Events(ctx context.Context, label string) (<-chan events.Message, <-chan error)
+
+	// GetNetworkByName returns the network with the given name, nil if it does not exist
+	GetNetworkByName(networkName string) (*types.NetworkResource, error)
+	// CreateNetwork creates a network and returns its ID
+	CreateNetwork(networkName string, labels map[string]string) (string, error)
+	// RemoveNetwork removes a network
+	RemoveNetwork(networkID string) error
+
+	// GetVolumeByName returns the volume with the given name, nil if it does not exist
+	GetVolumeByName(volumeName string) (*types.Volume, error)
+	// CreateVolume creates a named volume
+	CreateVolume(volumeName string, labels map[string]string) error
+
+	// Close releases the resources of the runtime
+	Close() error
+}
+
+// ContainerConfig holds the parameters of a created container
+type ContainerConfig struct {
+	// Container name
+	Name string
+	// Image name
+	Image string
+	// Optional image platform os[/arch[/variant]]
+	Platform string
+	// Environment variables as KEY=value
+	Env []string
+	// Port bindings as ip:hostPort:containerPort/proto
+	PortSpecs []string
+	// Command to run
+	Cmd []string
+	// Entrypoint, if empty the entrypoint of the image is used
+	Entrypoint []string
+	// Volume bindings
+	Binds []string
+	// Optional DNS server
+	DNSServer string
+	// Optional health check
+	Healthcheck *Healthcheck
+	// Container labels
+	Labels map[string]string
+	// Optional network the container is connected to
+	Network string
+	// Alias of the container in the network
+	NetworkAlias string
+}
+
+// implements Runtime
+var _ Runtime = (*dockerClient)(nil)
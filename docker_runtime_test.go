@@ -0,0 +1,358 @@
+package dockerit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	dit "github.com/grepplabs/docker-it"
+	"github.com/grepplabs/docker-it/dockerittest"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newFakeEnvironment(a *assert.Assertions, runtime *dockerittest.Runtime, components ...dit.DockerComponent) *dit.DockerEnvironment {
+	env, err := dit.NewDockerEnvironmentWithOptions([]dit.Option{dit.WithRuntime(runtime), dit.WithHost("127.0.0.1")}, components...)
+	a.Nil(err)
+	return env
+}
+
+func TestEnvironmentWithFakeRuntime(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime()
+	runtime.SetStartupLogs("app", "server started")
+	runtime.HandleExec("app", func(cmd []string, stdout io.Writer, stderr io.Writer) int {
+		fmt.Fprint(stdout, "pong")
+		return 0
+	})
+	env := newFakeEnvironment(a, runtime, dit.DockerComponent{
+		Name:         "app",
+		Image:        "busybox",
+		PullPolicy:   dit.PullIfNotPresent,
+		ExposedPorts: []dit.Port{{ContainerPort: 8080}},
+		Healthcheck:  &dit.Healthcheck{Test: []string{"CMD", "true"}},
+		FollowLogs:   true,
+	})
+	defer env.Shutdown()
+
+	a.Nil(env.Start("app"))
+	a.Equal("running", runtime.State("app"))
+	a.Equal([]string{"busybox"}, runtime.Pulled())
+	a.Nil(env.WaitForLog("app", "server started", time.Second))
+
+	var stdout bytes.Buffer
+	exitCode, err := env.Exec("app", []string{"ping"}, &stdout, nil)
+	a.Nil(err)
+	a.Equal(0, exitCode)
+	a.Equal("pong", stdout.String())
+
+	ip, err := env.Resolve(`{{ value . "app.IP" }}`)
+	a.Nil(err)
+	a.NotEmpty(ip)
+
+	a.Nil(env.Stop("app"))
+	a.Equal("exited", runtime.State("app"))
+	a.Nil(env.Destroy("app"))
+	a.Equal("", runtime.State("app"))
+}
+
+func TestEnvironmentWithFakeRuntimeNotClosed(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	env := newFakeEnvironment(a, runtime, dit.DockerComponent{Name: "app", Image: "busybox"})
+	a.Nil(env.Start("app"))
+	env.Shutdown()
+	env.Close()
+	a.False(runtime.Closed())
+}
+
+func TestEnvironmentWithFakeRuntimeVolumes(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	a.Nil(runtime.CreateVolume("existing", nil))
+	env := newFakeEnvironment(a, runtime, dit.DockerComponent{
+		Name:  "app",
+		Image: "busybox",
+		Binds: []string{"project_data:/data", "existing:/existing:ro", "/tmp:/host"},
+	})
+	defer env.Shutdown()
+	a.Nil(env.Start("app"))
+
+	// the created volume is labeled for Prune
+	volume, err := runtime.GetVolumeByName("project_data")
+	a.Nil(err)
+	a.NotNil(volume)
+	a.NotEmpty(volume.Labels[dit.LabelEnvironmentID])
+
+	volume, err = runtime.GetVolumeByName("existing")
+	a.Nil(err)
+	a.Empty(volume.Labels)
+
+	volume, err = runtime.GetVolumeByName("/tmp")
+	a.Nil(err)
+	a.Nil(volume)
+}
+
+func TestEnvironmentWithFakeRuntimeStartParallelism(t *testing.T) {
+	a := assert.New(t)
+
+	var running, maxRunning int32
+	hook := dit.HookFunc(func(componentName string, resolver dit.ValueResolver, component dit.Component) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	components := make([]dit.DockerComponent, 0)
+	names := make([]string, 0)
+	for i := 0; i < 6; i++ {
+		name := fmt.Sprintf("app%d", i)
+		names = append(names, name)
+		components = append(components, dit.DockerComponent{Name: name, Image: "busybox", Hooks: dit.Hooks{BeforeCreate: []dit.Hook{hook}}})
+	}
+	env, err := dit.NewDockerEnvironmentWithOptions([]dit.Option{dit.WithRuntime(dockerittest.NewRuntime("busybox")), dit.WithHost("127.0.0.1"), dit.WithParallelism(2)}, components...)
+	a.Nil(err)
+	defer env.Shutdown()
+
+	a.Nil(env.StartParallel(names...))
+	a.Equal(int32(2), atomic.LoadInt32(&maxRunning))
+}
+
+func TestEnvironmentWithFakeRuntimeFailures(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	runtime.SetStartupLogs("app", "starting", "bind failed")
+	runtime.SetHealth("app", "unhealthy")
+	env := newFakeEnvironment(a, runtime, dit.DockerComponent{
+		Name:          "app",
+		Image:         "busybox",
+		PullPolicy:    dit.PullNever,
+		Healthcheck:   &dit.Healthcheck{Test: []string{"CMD", "true"}},
+		ErrorLogLines: 1,
+	})
+	defer env.Shutdown()
+
+	err := env.Start("app")
+	a.NotNil(err)
+	a.Contains(err.Error(), "unhealthy")
+	a.Empty(runtime.Pulled())
+	var startError *dit.StartError
+	if a.True(errors.As(err, &startError)) {
+		a.Equal([]string{"bind failed"}, startError.Logs)
+	}
+	a.Nil(env.Destroy("app"))
+
+	runtime.SetHealth("app", "healthy")
+	runtime.Fail(dockerittest.OperationStart, "app", errors.New("port is already allocated"))
+	err = env.Start("app")
+	a.NotNil(err)
+	a.Contains(err.Error(), "port is already allocated")
+}
+
+func TestEnvironmentWithFakeRuntimeHealthcheckTimeout(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	runtime.SetHealth("app", "starting")
+	env := newFakeEnvironment(a, runtime,
+		dit.DockerComponent{
+			Name:  "app",
+			Image: "busybox",
+			Healthcheck: &dit.Healthcheck{
+				Test:     []string{"CMD", "true"},
+				Interval: 10 * time.Millisecond,
+				Timeout:  10 * time.Millisecond,
+				Retries:  1,
+			},
+		},
+		dit.DockerComponent{
+			Name:        "db",
+			Image:       "busybox",
+			Healthcheck: &dit.Healthcheck{},
+		},
+	)
+	defer env.Shutdown()
+
+	err := env.Start("app")
+	a.NotNil(err)
+	a.Contains(err.Error(), "DockerComponent [app] container is not healthy after 40ms, health status starting")
+
+	// the image does not define a health check
+	err = env.Start("db")
+	a.NotNil(err)
+	a.Contains(err.Error(), "DockerComponent [db] container has no health check")
+}
+
+func TestEnvironmentWithFakeRuntimeDumpLogs(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	runtime.SetStartupLogs("app", "app started")
+	runtime.SetStartupLogs("db", "db started")
+	env := newFakeEnvironment(a, runtime,
+		dit.DockerComponent{Name: "app", Image: "busybox"},
+		dit.DockerComponent{Name: "db", Image: "busybox"},
+	)
+	defer env.Shutdown()
+	a.Nil(env.Start("app", "db"))
+
+	// the container of app is removed behind the back of the environment
+	containerID, err := env.Resolve(`{{ value . "app.ContainerID" }}`)
+	a.Nil(err)
+	a.Nil(runtime.RemoveContainer(containerID))
+
+	var output bytes.Buffer
+	a.Nil(env.DumpLogs(&output))
+	a.Contains(output.String(), "DockerComponent [app] logs error")
+	a.Contains(output.String(), "db started")
+
+	// the log output of a single component
+	var stdout, stderr bytes.Buffer
+	a.Nil(env.WriteLogs("db", &stdout, &stderr))
+	a.Equal("db started\n", stdout.String())
+}
+
+func TestEnvironmentWithFakeRuntimeEvents(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	env := newFakeEnvironment(a, runtime, dit.DockerComponent{Name: "app", Image: "busybox"})
+	defer env.Shutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := env.Events(ctx)
+	a.Nil(env.Start("app"))
+	a.Nil(runtime.Exit("app", 1))
+
+	for _, expected := range []dit.EventType{dit.EventStart, dit.EventDie} {
+		select {
+		case event := <-events:
+			a.Equal(expected, event.Type)
+			a.Equal("app", event.ComponentName)
+			if expected == dit.EventDie {
+				a.Equal(1, event.ExitCode)
+				a.False(event.Expected)
+			}
+		case <-time.After(time.Second):
+			a.Fail("event expected", expected)
+		}
+	}
+
+	// the channel is closed when the context is canceled
+	cancel()
+	timeout := time.After(time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-events:
+			closed = !ok
+		case <-timeout:
+			a.FailNow("events channel not closed")
+		}
+	}
+}
+
+func TestEnvironmentWithFakeRuntimePlatform(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	env := newFakeEnvironment(a, runtime,
+		dit.DockerComponent{Name: "app", Image: "busybox", Platform: "linux/arm64/v8", PullPolicy: dit.PullIfNotPresent},
+		dit.DockerComponent{Name: "db", Image: "busybox", Platform: "linux/arm/v7", PullPolicy: dit.PullNever},
+	)
+	defer env.Shutdown()
+
+	// the local linux/amd64 image is replaced by the image of the required platform
+	a.Nil(env.Start("app"))
+	a.Equal([]string{"busybox"}, runtime.Pulled())
+	inspect, err := runtime.InspectImage("busybox")
+	a.Nil(err)
+	a.Equal("arm64", inspect.Architecture)
+	a.Equal("v8", inspect.Variant)
+
+	err = env.Start("db")
+	a.NotNil(err)
+	a.Contains(err.Error(), "Image busybox platform linux/arm64/v8 does not match required platform linux/arm/v7")
+}
+
+func TestEnvironmentWithFakeRuntimeSaveImagesRewritten(t *testing.T) {
+	a := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "docker-it")
+	a.Nil(err)
+	defer os.RemoveAll(dir)
+
+	component := dit.DockerComponent{Name: "db", Image: "postgres:9.6", PullPolicy: dit.PullIfNotPresent}
+	runtime := dockerittest.NewRuntime()
+	env := newFakeEnvironment(a, runtime, component)
+	env.SetImageRewriter(dit.NewRegistryMirrorRewriter(map[string]string{"docker.io": "mirror.example.com/hub"}))
+	a.Nil(env.SaveImages(dir))
+	a.Equal([]string{"mirror.example.com/hub/library/postgres:9.6"}, runtime.Pulled())
+	env.Shutdown()
+
+	// the archive named after the configured image is loaded with and without the rewrite
+	component.PullPolicy = dit.PullNever
+	component.ImageArchive = dit.ImageArchivePath(dir, "postgres:9.6")
+	for _, rewrite := range []bool{false, true} {
+		runtime := dockerittest.NewRuntime()
+		env := newFakeEnvironment(a, runtime, component)
+		if rewrite {
+			env.SetImageRewriter(dit.NewRegistryMirrorRewriter(map[string]string{"docker.io": "mirror.example.com/hub"}))
+		}
+		a.Nil(env.Start("db"), "rewrite %v", rewrite)
+		a.Equal("running", runtime.State("db"))
+		a.Empty(runtime.Pulled())
+		env.Shutdown()
+	}
+}
+
+func TestEnvironmentWithFakeRuntimeStartParallelRuntimeValues(t *testing.T) {
+	a := assert.New(t)
+
+	runtime := dockerittest.NewRuntime("busybox")
+	components := []dit.DockerComponent{{Name: "db", Image: "busybox"}}
+	names := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("app%d", i)
+		names = append(names, name)
+		// the values of the components started in parallel are resolved while their containers are created
+		components = append(components, dit.DockerComponent{
+			Name:                 name,
+			Image:                "busybox",
+			EnvironmentVariables: map[string]string{"DB_IP": `{{ value . "db.IP" }}`, "DB_NAME": `{{ value . "db.ContainerName" }}`},
+		})
+	}
+	env := newFakeEnvironment(a, runtime, components...)
+	defer env.Shutdown()
+
+	a.Nil(env.Start("db"))
+	done := make(chan struct{})
+	go func() {
+		// the values are read while the containers are created
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			env.Values()
+		}
+	}()
+	a.Nil(env.StartParallel(names...))
+	<-done
+	dbIP, err := env.Resolve(`{{ value . "db.IP" }}`)
+	a.Nil(err)
+	a.NotEmpty(dbIP)
+	a.NotEmpty(env.Values()["app4.ContainerID"])
+	a.Nil(env.Stop(names...))
+}
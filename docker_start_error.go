@@ -0,0 +1,74 @@
+package dockerit
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"strings"
+)
+
+const (
+	defaultErrorLogLines = 50
+)
+
+// StartError is returned when a component could not be started or its AfterStart callback failed.
+// Besides the cause it carries the container state and the last lines of the container log output.
+type StartError struct {
+	// Name of the docker component
+	ComponentName string
+	// ID of the component container, empty if the container was not created
+	ContainerID string
+	// Container state description
+	State string
+	// Last lines of the container log output
+	Logs []string
+	// Error causing the failure
+	Err error
+}
+
+// implements error
+func (e *StartError) Error() string {
+	var b bytes.Buffer
+	b.WriteString(e.Err.Error())
+	if e.State != "" {
+		fmt.Fprintf(&b, "\nDockerComponent [%s] container %s state: %s", e.ComponentName, TruncateID(e.ContainerID), e.State)
+	}
+	if len(e.Logs) != 0 {
+		fmt.Fprintf(&b, "\nLast %d log lines of DockerComponent [%s]:", len(e.Logs), e.ComponentName)
+		for _, line := range e.Logs {
+			b.WriteString("\n\t")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// Cause returns the error causing the failure
+func (e *StartError) Cause() error {
+	return e.Err
+}
+
+// Unwrap returns the error causing the failure, see errors.Is and errors.As
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
+
+func describeContainerState(state *types.ContainerState) string {
+	if state == nil {
+		return ""
+	}
+	description := []string{state.Status}
+	if !state.Running {
+		description = append(description, fmt.Sprintf("exit code %d", state.ExitCode))
+	}
+	if state.OOMKilled {
+		description = append(description, "OOM killed")
+	}
+	if state.Health != nil && state.Health.Status != "" {
+		description = append(description, "health "+state.Health.Status)
+	}
+	if state.Error != "" {
+		description = append(description, "error "+state.Error)
+	}
+	return strings.Join(description, ", ")
+}
@@ -0,0 +1,64 @@
+package dockerit
+
+import (
+	"errors"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStartErrorWithoutContainer(t *testing.T) {
+	a := assert.New(t)
+
+	err := &StartError{ComponentName: "it-es", Err: errors.New("Local images es does not exist")}
+	a.EqualError(err, "Local images es does not exist")
+	a.EqualError(err.Cause(), "Local images es does not exist")
+}
+
+func TestStartErrorUnwrap(t *testing.T) {
+	a := assert.New(t)
+
+	cause := &DaemonError{Reason: DaemonUnreachable, Err: errors.New("connection refused")}
+	var err error = &StartError{ComponentName: "it-es", Err: cause}
+	a.True(errors.Is(err, cause))
+	var daemonError *DaemonError
+	a.True(errors.As(err, &daemonError))
+	a.Equal(DaemonUnreachable, daemonError.Reason)
+}
+
+func TestStartErrorWithStateAndLogs(t *testing.T) {
+	a := assert.New(t)
+
+	err := &StartError{
+		ComponentName: "it-es",
+		ContainerID:   "0fae9d0ac54a0fae9d0ac54a",
+		State:         "exited, exit code 1",
+		Logs:          []string{"starting", "bootstrap checks failed"},
+		Err:           errors.New("Readiness probe of 'it-es' failed"),
+	}
+	a.EqualError(err, "Readiness probe of 'it-es' failed\n"+
+		"DockerComponent [it-es] container 0fae9d0ac54a state: exited, exit code 1\n"+
+		"Last 2 log lines of DockerComponent [it-es]:\n"+
+		"\tstarting\n"+
+		"\tbootstrap checks failed")
+}
+
+func TestDescribeContainerState(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("", describeContainerState(nil))
+	a.Equal("running, health starting", describeContainerState(&types.ContainerState{
+		Status:  "running",
+		Running: true,
+		Health:  &types.Health{Status: "starting"},
+	}))
+	a.Equal("exited, exit code 137, OOM killed", describeContainerState(&types.ContainerState{
+		Status:    "exited",
+		ExitCode:  137,
+		OOMKilled: true,
+	}))
+	a.Equal("created, exit code 0, error port is already allocated", describeContainerState(&types.ContainerState{
+		Status: "created",
+		Error:  "port is already allocated",
+	}))
+}
@@ -0,0 +1,94 @@
+package dockerit
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	// skipEnv skips the tests using New, if set
+	skipEnv = "DOCKER_IT_SKIP"
+)
+
+// New creates a docker test environment for the test and starts the components in the given order.
+// The environment is shut down when the test and its subtests finished, the library and container log output is written with t.Log.
+// The test is skipped if DOCKER_IT_SKIP is set or the docker daemon is not reachable. If a component cannot be started,
+// the test fails with the container state and the last lines of the container log output.
+func New(t testing.TB, components ...DockerComponent) *DockerEnvironment {
+	t.Helper()
+	if value := os.Getenv(skipEnv); value != "" {
+		t.Skipf("%s is set", skipEnv)
+	}
+	if err := checkDaemon(ClientConfig{}); err != nil {
+		t.Skipf("Docker daemon is not reachable: %v", err)
+	}
+	return newTestEnvironment(t, nil, components...)
+}
+
+// newTestEnvironment creates the environment with the options and the logger writing with t.Log, and starts the components
+func newTestEnvironment(t testing.TB, options []Option, components ...DockerComponent) *DockerEnvironment {
+	t.Helper()
+	output := &testLogWriter{t: t}
+	components = append([]DockerComponent(nil), components...)
+	names := make([]string, 0, len(components))
+	for i, component := range components {
+		if component.LogConsumer == nil {
+			components[i].LogConsumer = &testLogConsumer{output: output}
+		}
+		names = append(names, component.Name)
+	}
+	// the logger is set at construction, which already logs
+	options = append([]Option{WithLogger(log.New(output, "", log.Ldate|log.Ltime))}, options...)
+	env, err := NewDockerEnvironmentWithOptions(options, components...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		env.Shutdown()
+		// goroutines following the container logs may outlive the test
+		output.close()
+	})
+	if err := env.Start(names...); err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// testLogWriter writes the log output with t.Log until it is closed
+type testLogWriter struct {
+	mutex  sync.Mutex
+	t      testing.TB
+	closed bool
+}
+
+// implements io.Writer interface
+func (w *testLogWriter) Write(b []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !w.closed {
+		w.t.Log(strings.TrimRight(string(b), "\n"))
+	}
+	return len(b), nil
+}
+
+func (w *testLogWriter) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+}
+
+// testLogConsumer writes the container log output with the component name as prefix
+type testLogConsumer struct {
+	output io.Writer
+}
+
+// implements LogConsumer
+func (r *testLogConsumer) Writers(componentName string) (io.Writer, io.Writer, error) {
+	out := &logWriter{log.New(r.output, fmt.Sprintf("%s: ", componentName), 0)}
+	return out, out, nil
+}
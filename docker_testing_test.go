@@ -0,0 +1,88 @@
+package dockerit
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestNewSkipsWhenSkipEnvIsSet(t *testing.T) {
+	a := assert.New(t)
+
+	os.Setenv(skipEnv, "true")
+	defer os.Unsetenv(skipEnv)
+
+	var skipped *testing.T
+	t.Run("skip", func(t *testing.T) {
+		skipped = t
+		New(t, DockerComponent{Name: "it-redis", Image: "redis"})
+		t.Error("test was not skipped")
+	})
+	a.True(skipped.Skipped())
+}
+
+type recordingTB struct {
+	testing.TB
+	lines    []string
+	cleanups []func()
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Log(args ...interface{}) {
+	r.lines = append(r.lines, args[0].(string))
+}
+
+func (r *recordingTB) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+// Fatal records the failure and stops the goroutine like testing.T
+func (r *recordingTB) Fatal(args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprint(args...))
+	runtime.Goexit()
+}
+
+// nopRuntime panics if any runtime method is called
+type nopRuntime struct {
+	Runtime
+}
+
+func TestNewTestEnvironmentLogsConstruction(t *testing.T) {
+	a := assert.New(t)
+
+	tb := &recordingTB{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		newTestEnvironment(tb, []Option{WithRuntime(nopRuntime{}), WithHost("127.0.0.1")}, DockerComponent{Name: "it-redis"})
+	}()
+	<-done
+
+	a.Equal([]string{"DockerComponent Name and Image must not be empty"}, tb.failures)
+	// the construction logs with t.Log
+	a.NotEmpty(tb.lines)
+	a.Contains(tb.lines[0], "Using IP 127.0.0.1")
+}
+
+func TestTestLogWriter(t *testing.T) {
+	a := assert.New(t)
+
+	tb := &recordingTB{}
+	output := &testLogWriter{t: tb}
+	stdout, stderr, err := (&testLogConsumer{output: output}).Writers("it-redis")
+	a.Nil(err)
+	stdout.Write([]byte("Ready to accept connections\n"))
+	stderr.Write([]byte("WARNING overcommit_memory\n"))
+	newWriterLogger(output).Info.Println("Stop component it-redis")
+	output.close()
+	stdout.Write([]byte("after the test\n"))
+
+	a.Len(tb.lines, 3)
+	a.Equal("it-redis: Ready to accept connections", tb.lines[0])
+	a.Equal("it-redis: WARNING overcommit_memory", tb.lines[1])
+	a.Contains(tb.lines[2], "Stop component it-redis")
+}
@@ -0,0 +1,450 @@
+package dockerittest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	typesContainer "github.com/docker/docker/api/types/container"
+	typesNetwork "github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	dit "github.com/grepplabs/docker-it"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	stateCreated = "created"
+	stateRunning = "running"
+	stateExited  = "exited"
+
+	// number of log lines buffered for a follower, lines of slow followers are dropped
+	followBufferSize = 1000
+)
+
+type logStream int
+
+const (
+	stdoutStream logStream = iota
+	stderrStream
+)
+
+type logLine struct {
+	time   time.Time
+	stream logStream
+	text   string
+}
+
+type container struct {
+	id         string
+	name       string
+	config     dit.ContainerConfig
+	state      string
+	exitCode   int
+	oomKilled  bool
+	health     string
+	ip         string
+	created    time.Time
+	startedAt  time.Time
+	finishedAt time.Time
+	logs       []logLine
+	followers  map[chan logLine]struct{}
+}
+
+func (c *container) component() string {
+	return dit.NormalizeName(c.config.Labels[dit.LabelComponent])
+}
+
+// appendLog stores the log line and passes it to the followers
+func (c *container) appendLog(stream logStream, text string) {
+	line := logLine{time: time.Now(), stream: stream, text: text}
+	c.logs = append(c.logs, line)
+	for follower := range c.followers {
+		select {
+		case follower <- line:
+		default:
+		}
+	}
+}
+
+// closeFollowers ends the followed logs, when the container is stopped
+func (c *container) closeFollowers() {
+	for follower := range c.followers {
+		close(follower)
+	}
+	c.followers = make(map[chan logLine]struct{})
+}
+
+// implements dit.Runtime
+func (r *Runtime) GetContainerByID(containerID string) (*types.Container, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, ok := r.containers[containerID]
+	if !ok {
+		return nil, nil
+	}
+	return c.summary(), nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) GetContainerByName(containerName string) (*types.Container, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	name := strings.TrimPrefix(containerName, "/")
+	for _, c := range r.containers {
+		if c.name == name {
+			return c.summary(), nil
+		}
+	}
+	return nil, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) CreateContainer(config dit.ContainerConfig) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.failure(OperationCreate, config.Labels[dit.LabelComponent]); err != nil {
+		return "", err
+	}
+	if _, ok := r.images[normalizeImage(config.Image)]; !ok {
+		return "", fmt.Errorf("No such image: %s", config.Image)
+	}
+	for _, c := range r.containers {
+		if c.name == config.Name {
+			return "", fmt.Errorf("Conflict. The container name \"/%s\" is already in use by container %s", config.Name, c.id)
+		}
+	}
+	config.Labels = copyLabels(config.Labels)
+	c := &container{
+		id:        r.newID(config.Name),
+		name:      config.Name,
+		config:    config,
+		state:     stateCreated,
+		created:   time.Now(),
+		followers: make(map[chan logLine]struct{}),
+	}
+	r.containers[c.id] = c
+	return c.id, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) StartContainer(containerID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if err := r.failure(OperationStart, c.component()); err != nil {
+		return err
+	}
+	if c.state == stateRunning {
+		return nil
+	}
+	c.state = stateRunning
+	c.startedAt = time.Now()
+	c.exitCode = 0
+	c.oomKilled = false
+	if c.ip == "" {
+		c.ip = fmt.Sprintf("172.17.%d.%d", (r.sequence/254)%256, r.sequence%254+1)
+	}
+	c.health = ""
+	if healthcheck := c.config.Healthcheck; healthcheck != nil && !(len(healthcheck.Test) == 1 && healthcheck.Test[0] == "NONE") {
+		status, ok := r.health[c.component()]
+		if ok {
+			c.health = status
+		} else if len(healthcheck.Test) != 0 {
+			c.health = "healthy"
+		}
+	}
+	for _, line := range r.startupLogs[c.component()] {
+		c.appendLog(stdoutStream, line)
+	}
+	r.emit(c, "start", nil)
+	if c.health != "" {
+		r.emit(c, "health_status: "+c.health, nil)
+	}
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) StopContainer(containerID string, timeout *time.Duration) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if err := r.failure(OperationStop, c.component()); err != nil {
+		return err
+	}
+	if c.state == stateRunning {
+		r.stop(c, 0)
+	}
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) RemoveContainer(containerID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getContainer(containerID)
+	if err != nil {
+		return err
+	}
+	if err := r.failure(OperationRemove, c.component()); err != nil {
+		return err
+	}
+	if c.state == stateRunning {
+		r.stop(c, 137)
+	}
+	delete(r.containers, containerID)
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) InspectContainer(containerID string) (*types.ContainerJSON, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getContainer(containerID)
+	if err != nil {
+		return nil, err
+	}
+	_, portBindings, err := nat.ParsePortSpecs(c.config.PortSpecs)
+	if err != nil {
+		return nil, err
+	}
+	state := &types.ContainerState{
+		Status:     c.state,
+		Running:    c.state == stateRunning,
+		OOMKilled:  c.oomKilled,
+		ExitCode:   c.exitCode,
+		StartedAt:  formatTime(c.startedAt),
+		FinishedAt: formatTime(c.finishedAt),
+	}
+	if c.health != "" {
+		state.Health = &types.Health{Status: c.health}
+	}
+	networkMode := "default"
+	if c.config.Network != "" {
+		networkMode = c.config.Network
+	}
+	settings := &types.NetworkSettings{}
+	if c.state == stateRunning {
+		if c.config.Network == "" {
+			settings.IPAddress = c.ip
+		}
+		endpoint := &typesNetwork.EndpointSettings{IPAddress: c.ip}
+		if c.config.NetworkAlias != "" {
+			endpoint.Aliases = []string{c.config.NetworkAlias}
+		}
+		networkName := c.config.Network
+		if networkName == "" {
+			networkName = "bridge"
+		}
+		settings.Networks = map[string]*typesNetwork.EndpointSettings{networkName: endpoint}
+	}
+	return &types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:      c.id,
+			Created: formatTime(c.created),
+			Path:    strings.Join(c.config.Entrypoint, " "),
+			Args:    c.config.Cmd,
+			State:   state,
+			Image:   r.images[normalizeImage(c.config.Image)],
+			Name:    "/" + c.name,
+			HostConfig: &typesContainer.HostConfig{
+				Binds:        c.config.Binds,
+				NetworkMode:  typesContainer.NetworkMode(networkMode),
+				PortBindings: portBindings,
+			},
+		},
+		Config: &typesContainer.Config{
+			Env:        c.config.Env,
+			Cmd:        c.config.Cmd,
+			Image:      c.config.Image,
+			Entrypoint: c.config.Entrypoint,
+			Labels:     copyLabels(c.config.Labels),
+		},
+		NetworkSettings: settings,
+	}, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) ContainerLogs(containerID string, follow bool, timestamps bool, since time.Time) (io.ReadCloser, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getContainer(containerID)
+	if err != nil {
+		return nil, err
+	}
+	lines := make([]logLine, 0, len(c.logs))
+	for _, line := range c.logs {
+		if since.IsZero() || !line.time.Before(since) {
+			lines = append(lines, line)
+		}
+	}
+	if !follow || c.state != stateRunning {
+		var buffer bytes.Buffer
+		for _, line := range lines {
+			writeLogLine(&buffer, line, timestamps)
+		}
+		return ioutil.NopCloser(&buffer), nil
+	}
+
+	follower := make(chan logLine, followBufferSize)
+	c.followers[follower] = struct{}{}
+	pipeReader, writer := io.Pipe()
+	reader := &followReader{PipeReader: pipeReader, closed: make(chan struct{})}
+	go func() {
+		defer writer.Close()
+		for _, line := range lines {
+			if err := writeLogLine(writer, line, timestamps); err != nil {
+				r.unfollow(c, follower)
+				return
+			}
+		}
+		for {
+			select {
+			case line, ok := <-follower:
+				if !ok {
+					return
+				}
+				if err := writeLogLine(writer, line, timestamps); err != nil {
+					r.unfollow(c, follower)
+					return
+				}
+			case <-reader.closed:
+				r.unfollow(c, follower)
+				return
+			}
+		}
+	}()
+	return reader, nil
+}
+
+// followReader signals the close, so the followed logs are not written anymore
+type followReader struct {
+	*io.PipeReader
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (r *followReader) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return r.PipeReader.Close()
+}
+
+// implements dit.Runtime
+func (r *Runtime) ExecContainer(containerID string, cmd []string, stdout io.Writer, stderr io.Writer) (int, error) {
+	r.mutex.Lock()
+	c, err := r.getContainer(containerID)
+	if err == nil && c.state != stateRunning {
+		err = fmt.Errorf("Container %s is not running", containerID)
+	}
+	if err == nil {
+		err = r.failure(OperationExec, c.component())
+	}
+	if err != nil {
+		r.mutex.Unlock()
+		return 0, err
+	}
+	handler := r.execHandlers[c.component()]
+	r.mutex.Unlock()
+
+	if handler == nil {
+		return 0, nil
+	}
+	if stdout == nil {
+		stdout = ioutil.Discard
+	}
+	if stderr == nil {
+		stderr = ioutil.Discard
+	}
+	return handler(cmd, stdout, stderr), nil
+}
+
+// stop changes the state of the running container to exited
+func (r *Runtime) stop(c *container, exitCode int) {
+	c.state = stateExited
+	c.exitCode = exitCode
+	c.finishedAt = time.Now()
+	c.closeFollowers()
+	r.emit(c, "die", map[string]string{"exitCode": strconv.Itoa(exitCode)})
+}
+
+func (r *Runtime) unfollow(c *container, follower chan logLine) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(c.followers, follower)
+}
+
+func (r *Runtime) getContainer(containerID string) (*container, error) {
+	c, ok := r.containers[containerID]
+	if !ok {
+		return nil, fmt.Errorf("No such container: %s", containerID)
+	}
+	return c, nil
+}
+
+// findContainer returns the container of the component, nil if it does not exist
+func (r *Runtime) findContainer(componentName string) *container {
+	for _, c := range r.containers {
+		if c.component() == componentName {
+			return c
+		}
+	}
+	return nil
+}
+
+func (r *Runtime) getRunningContainer(componentName string) (*container, error) {
+	c := r.findContainer(dit.NormalizeName(componentName))
+	if c == nil || c.state != stateRunning {
+		return nil, fmt.Errorf("Component %s is not running", componentName)
+	}
+	return c, nil
+}
+
+func (c *container) summary() *types.Container {
+	status := "Created"
+	switch c.state {
+	case stateRunning:
+		status = "Up"
+	case stateExited:
+		status = fmt.Sprintf("Exited (%d)", c.exitCode)
+	}
+	return &types.Container{
+		ID:      c.id,
+		Names:   []string{"/" + c.name},
+		Image:   c.config.Image,
+		Created: c.created.Unix(),
+		Labels:  copyLabels(c.config.Labels),
+		State:   c.state,
+		Status:  status,
+	}
+}
+
+// writeLogLine writes the line multiplexed as by the docker API
+func writeLogLine(w io.Writer, line logLine, timestamps bool) error {
+	streamType := stdcopy.Stdout
+	if line.stream == stderrStream {
+		streamType = stdcopy.Stderr
+	}
+	text := line.text + "\n"
+	if timestamps {
+		text = line.time.UTC().Format(time.RFC3339Nano) + " " + text
+	}
+	_, err := stdcopy.NewStdWriter(w, streamType).Write([]byte(text))
+	return err
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "0001-01-01T00:00:00Z"
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,511 @@
+// Package dockerittest provides an in-memory container runtime to unit test docker environments without a docker daemon.
+package dockerittest
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	dit "github.com/grepplabs/docker-it"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// number of events buffered for a subscriber, events of slow subscribers are dropped
+	eventBufferSize = 100
+	// manifest of the image archives created by docker save
+	manifestFile = "manifest.json"
+)
+
+// Operation is a runtime operation, which can be configured to fail, see Runtime.Fail
+type Operation string
+
+const (
+	// OperationPull pulls an image, the failure is configured for the image name
+	OperationPull Operation = "pull"
+	// OperationCreate creates the container of a component
+	OperationCreate Operation = "create"
+	// OperationStart starts the container of a component
+	OperationStart Operation = "start"
+	// OperationStop stops the container of a component
+	OperationStop Operation = "stop"
+	// OperationRemove removes the container of a component
+	OperationRemove Operation = "remove"
+	// OperationExec runs a command in the container of a component
+	OperationExec Operation = "exec"
+)
+
+// ExecHandler simulates a command run in a container and returns its exit code
+type ExecHandler func(cmd []string, stdout io.Writer, stderr io.Writer) int
+
+type failureKey struct {
+	operation Operation
+	name      string
+}
+
+type subscriber struct {
+	key      string
+	value    string
+	messages chan events.Message
+}
+
+// Runtime is an in-memory dit.Runtime. The containers do not run any process, their state changes
+// with the runtime calls and with the simulated crashes, health changes and log output.
+type Runtime struct {
+	mutex        sync.Mutex
+	sequence     int
+	images       map[string]string
+	platforms    map[string]string
+	pulled       []string
+	containers   map[string]*container
+	networks     map[string]*types.NetworkResource
+	volumes      map[string]*types.Volume
+	failures     map[failureKey]error
+	startupLogs  map[string][]string
+	health       map[string]string
+	execHandlers map[string]ExecHandler
+	subscribers  map[*subscriber]struct{}
+	closed       bool
+}
+
+// implements dit.Runtime
+var _ dit.Runtime = (*Runtime)(nil)
+
+// NewRuntime creates an in-memory runtime with the given local images
+func NewRuntime(images ...string) *Runtime {
+	r := &Runtime{
+		images:       make(map[string]string),
+		platforms:    make(map[string]string),
+		containers:   make(map[string]*container),
+		networks:     make(map[string]*types.NetworkResource),
+		volumes:      make(map[string]*types.Volume),
+		failures:     make(map[failureKey]error),
+		startupLogs:  make(map[string][]string),
+		health:       make(map[string]string),
+		execHandlers: make(map[string]ExecHandler),
+		subscribers:  make(map[*subscriber]struct{}),
+	}
+	r.AddImages(images...)
+	return r
+}
+
+// AddImages adds local images, which do not need to be pulled
+func (r *Runtime) AddImages(images ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, image := range images {
+		r.addImage(image)
+	}
+}
+
+// Pulled returns the names of the pulled images in the pull order
+func (r *Runtime) Pulled() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.pulled...)
+}
+
+// Fail makes the operation fail with err for the component, or for the image of OperationPull.
+// A nil err removes the failure.
+func (r *Runtime) Fail(operation Operation, name string, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	key := failureKey{operation: operation, name: failureName(operation, name)}
+	if err == nil {
+		delete(r.failures, key)
+	} else {
+		r.failures[key] = err
+	}
+}
+
+// SetStartupLogs sets the lines written to stdout whenever the container of the component is started
+func (r *Runtime) SetStartupLogs(componentName string, lines ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.startupLogs[dit.NormalizeName(componentName)] = lines
+}
+
+// WriteLog writes a line to stdout of the running container of the component
+func (r *Runtime) WriteLog(componentName string, line string) error {
+	return r.writeLog(componentName, stdoutStream, line)
+}
+
+// WriteErrorLog writes a line to stderr of the running container of the component
+func (r *Runtime) WriteErrorLog(componentName string, line string) error {
+	return r.writeLog(componentName, stderrStream, line)
+}
+
+func (r *Runtime) writeLog(componentName string, stream logStream, line string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getRunningContainer(componentName)
+	if err != nil {
+		return err
+	}
+	c.appendLog(stream, line)
+	return nil
+}
+
+// SetHealth sets the health status (starting, healthy or unhealthy) of the container of the component.
+// Containers with a health check are healthy after the start, unless another status is set. A health check
+// without a test inherits the image health check, which the images of the runtime do not have unless a status is set.
+func (r *Runtime) SetHealth(componentName string, status string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	name := dit.NormalizeName(componentName)
+	r.health[name] = status
+	if c := r.findContainer(name); c != nil && c.state == stateRunning && c.health != "" && c.health != status {
+		c.health = status
+		r.emit(c, "health_status: "+status, nil)
+	}
+}
+
+// HandleExec sets the handler of the commands run in the container of the component.
+// Without a handler commands succeed without output.
+func (r *Runtime) HandleExec(componentName string, handler ExecHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.execHandlers[dit.NormalizeName(componentName)] = handler
+}
+
+// Exit simulates the exit of the running container of the component, e.g. a crash
+func (r *Runtime) Exit(componentName string, exitCode int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getRunningContainer(componentName)
+	if err != nil {
+		return err
+	}
+	r.stop(c, exitCode)
+	return nil
+}
+
+// OOM simulates the running container of the component being killed because it ran out of memory
+func (r *Runtime) OOM(componentName string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	c, err := r.getRunningContainer(componentName)
+	if err != nil {
+		return err
+	}
+	r.emit(c, "oom", nil)
+	c.oomKilled = true
+	r.stop(c, 137)
+	return nil
+}
+
+// State returns the state (created, running or exited) of the container of the component, empty if it does not exist
+func (r *Runtime) State(componentName string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if c := r.findContainer(dit.NormalizeName(componentName)); c != nil {
+		return c.state
+	}
+	return ""
+}
+
+// implements dit.Runtime
+func (r *Runtime) GetImageByName(imageName string) (*types.ImageSummary, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	name := normalizeImage(imageName)
+	id, ok := r.images[name]
+	if !ok {
+		return nil, nil
+	}
+	return &types.ImageSummary{ID: id, RepoTags: []string{name}}, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) InspectImage(imageName string) (*types.ImageInspect, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	name := normalizeImage(imageName)
+	id, ok := r.images[name]
+	if !ok {
+		return nil, fmt.Errorf("No such image: %s", imageName)
+	}
+	inspect := &types.ImageInspect{ID: id, RepoTags: []string{name}, Os: "linux", Architecture: "amd64"}
+	if platform, ok := r.platforms[name]; ok {
+		parts := strings.SplitN(platform+"//", "/", 3)
+		inspect.Os, inspect.Architecture, inspect.Variant = parts[0], parts[1], strings.TrimRight(parts[2], "/")
+	}
+	return inspect, nil
+}
+
+// implements dit.Runtime, the pulled image reports the requested platform, linux/amd64 by default
+func (r *Runtime) PullImage(imageName string, platform string, registryAuth string, logger *log.Logger) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.failure(OperationPull, imageName); err != nil {
+		return err
+	}
+	r.addImage(imageName)
+	if platform == "" {
+		delete(r.platforms, normalizeImage(imageName))
+	} else {
+		r.platforms[normalizeImage(imageName)] = platform
+	}
+	r.pulled = append(r.pulled, imageName)
+	if logger != nil {
+		logger.Println("Pulled image", imageName)
+	}
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) LoadImage(archive io.Reader) error {
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("Image archive does not contain %s", manifestFile)
+		}
+		if err != nil {
+			return err
+		}
+		if header.Name != manifestFile {
+			continue
+		}
+		var manifest []struct{ RepoTags []string }
+		if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+			return err
+		}
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		for _, entry := range manifest {
+			for _, image := range entry.RepoTags {
+				r.addImage(image)
+			}
+		}
+		return nil
+	}
+}
+
+// implements dit.Runtime
+func (r *Runtime) SaveImages(imageNames []string, w io.Writer) error {
+	r.mutex.Lock()
+	repoTags := make([]string, 0, len(imageNames))
+	for _, imageName := range imageNames {
+		name := normalizeImage(imageName)
+		if _, ok := r.images[name]; !ok {
+			r.mutex.Unlock()
+			return fmt.Errorf("No such image: %s", imageName)
+		}
+		repoTags = append(repoTags, name)
+	}
+	r.mutex.Unlock()
+
+	manifest, err := json.Marshal([]struct{ RepoTags []string }{{RepoTags: repoTags}})
+	if err != nil {
+		return err
+	}
+	writer := tar.NewWriter(w)
+	if err := writer.WriteHeader(&tar.Header{Name: manifestFile, Mode: 0644, Size: int64(len(manifest))}); err != nil {
+		return err
+	}
+	if _, err := writer.Write(manifest); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// implements dit.Runtime
+func (r *Runtime) TagImage(source string, target string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	id, ok := r.images[normalizeImage(source)]
+	if !ok {
+		return fmt.Errorf("No such image: %s", source)
+	}
+	r.images[normalizeImage(target)] = id
+	if platform, ok := r.platforms[normalizeImage(source)]; ok {
+		r.platforms[normalizeImage(target)] = platform
+	} else {
+		delete(r.platforms, normalizeImage(target))
+	}
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) RemoveImageByName(imageName string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.images, normalizeImage(imageName))
+	delete(r.platforms, normalizeImage(imageName))
+	return nil
+}
+
+// implements dit.Runtime, the messages channel is closed when the context is done
+func (r *Runtime) Events(ctx context.Context, label string) (<-chan events.Message, <-chan error) {
+	s := &subscriber{messages: make(chan events.Message, eventBufferSize)}
+	s.key = label
+	if i := strings.Index(label, "="); i >= 0 {
+		s.key, s.value = label[:i], label[i+1:]
+	}
+	errs := make(chan error, 1)
+
+	r.mutex.Lock()
+	r.subscribers[s] = struct{}{}
+	r.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		delete(r.subscribers, s)
+		close(s.messages)
+	}()
+	return s.messages, errs
+}
+
+// implements dit.Runtime
+func (r *Runtime) GetNetworkByName(networkName string) (*types.NetworkResource, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, network := range r.networks {
+		if network.Name == networkName {
+			result := *network
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) CreateNetwork(networkName string, labels map[string]string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, network := range r.networks {
+		if network.Name == networkName {
+			return "", fmt.Errorf("network with name %s already exists", networkName)
+		}
+	}
+	id := r.newID(networkName)
+	r.networks[id] = &types.NetworkResource{Name: networkName, ID: id, Created: time.Now(), Driver: "bridge", Labels: copyLabels(labels)}
+	return id, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) RemoveNetwork(networkID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.networks[networkID]; !ok {
+		return fmt.Errorf("No such network: %s", networkID)
+	}
+	delete(r.networks, networkID)
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) GetVolumeByName(volumeName string) (*types.Volume, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if volume, ok := r.volumes[volumeName]; ok {
+		result := *volume
+		return &result, nil
+	}
+	return nil, nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) CreateVolume(volumeName string, labels map[string]string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	// as docker, an existing volume is kept
+	if _, ok := r.volumes[volumeName]; !ok {
+		r.volumes[volumeName] = &types.Volume{Name: volumeName, Driver: "local", CreatedAt: time.Now().Format(time.RFC3339), Labels: copyLabels(labels)}
+	}
+	return nil
+}
+
+// implements dit.Runtime
+func (r *Runtime) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+	return nil
+}
+
+// Closed reports whether the runtime was closed
+func (r *Runtime) Closed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.closed
+}
+
+func (r *Runtime) addImage(imageName string) {
+	name := normalizeImage(imageName)
+	if _, ok := r.images[name]; !ok {
+		r.images[name] = "sha256:" + r.newID(name)
+	}
+}
+
+func (r *Runtime) failure(operation Operation, name string) error {
+	return r.failures[failureKey{operation: operation, name: failureName(operation, name)}]
+}
+
+// emit sends the event of the container to the subscribers of its labels
+func (r *Runtime) emit(c *container, action string, attributes map[string]string) {
+	now := time.Now()
+	actorAttributes := copyLabels(c.config.Labels)
+	actorAttributes["image"] = c.config.Image
+	actorAttributes["name"] = c.name
+	for k, v := range attributes {
+		actorAttributes[k] = v
+	}
+	message := events.Message{
+		Status:   action,
+		ID:       c.id,
+		From:     c.config.Image,
+		Type:     "container",
+		Action:   action,
+		Actor:    events.Actor{ID: c.id, Attributes: actorAttributes},
+		Time:     now.Unix(),
+		TimeNano: now.UnixNano(),
+	}
+	for s := range r.subscribers {
+		if value, ok := c.config.Labels[s.key]; !ok || (s.value != "" && value != s.value) {
+			continue
+		}
+		select {
+		case s.messages <- message:
+		default:
+		}
+	}
+}
+
+func (r *Runtime) newID(name string) string {
+	r.sequence++
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(fmt.Sprintf("%s-%d", name, r.sequence))))
+}
+
+func failureName(operation Operation, name string) string {
+	if operation == OperationPull {
+		return normalizeImage(name)
+	}
+	return dit.NormalizeName(name)
+}
+
+// normalizeImage adds the latest tag to untagged images
+func normalizeImage(image string) string {
+	if strings.Contains(image, "@") || strings.Contains(image[strings.LastIndex(image, "/")+1:], ":") {
+		return image
+	}
+	return image + ":latest"
+}
+
+func copyLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
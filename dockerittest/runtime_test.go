@@ -0,0 +1,347 @@
+package dockerittest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/pkg/stdcopy"
+	dit "github.com/grepplabs/docker-it"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func newTestContainer(a *assert.Assertions, r *Runtime, componentName string, healthcheck *dit.Healthcheck) string {
+	id, err := r.CreateContainer(dit.ContainerConfig{
+		Name:        componentName + "-test",
+		Image:       "busybox",
+		Healthcheck: healthcheck,
+		Labels:      map[string]string{dit.LabelComponent: componentName, dit.LabelEnvironmentID: "test"},
+	})
+	a.Nil(err)
+	return id
+}
+
+func readLogs(a *assert.Assertions, reader io.Reader) (string, string) {
+	var stdout, stderr bytes.Buffer
+	_, err := stdcopy.StdCopy(&stdout, &stderr, reader)
+	a.Nil(err)
+	return stdout.String(), stderr.String()
+}
+
+func TestImages(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	summary, err := r.GetImageByName("busybox:latest")
+	a.Nil(err)
+	a.NotNil(summary)
+
+	summary, err = r.GetImageByName("redis")
+	a.Nil(err)
+	a.Nil(summary)
+
+	a.Nil(r.PullImage("redis", "", "", nil))
+	summary, err = r.GetImageByName("redis")
+	a.Nil(err)
+	a.NotNil(summary)
+	a.Equal([]string{"redis"}, r.Pulled())
+
+	r.Fail(OperationPull, "postgres:latest", errors.New("pull denied"))
+	a.EqualError(r.PullImage("postgres", "", "", nil), "pull denied")
+	r.Fail(OperationPull, "postgres", nil)
+	a.Nil(r.PullImage("postgres", "linux/arm64/v8", "", nil))
+
+	inspect, err := r.InspectImage("redis")
+	a.Nil(err)
+	a.Equal("linux", inspect.Os)
+	a.Equal("amd64", inspect.Architecture)
+	inspect, err = r.InspectImage("postgres")
+	a.Nil(err)
+	a.Equal("arm64", inspect.Architecture)
+	a.Equal("v8", inspect.Variant)
+	_, err = r.InspectImage("mysql")
+	a.NotNil(err)
+
+	var archive bytes.Buffer
+	a.Nil(r.SaveImages([]string{"redis"}, &archive))
+	a.NotNil(r.SaveImages([]string{"mysql"}, ioutil.Discard))
+
+	loaded := NewRuntime()
+	a.Nil(loaded.LoadImage(&archive))
+	summary, err = loaded.GetImageByName("redis")
+	a.Nil(err)
+	a.NotNil(summary)
+
+	a.Nil(r.TagImage("postgres", "mirror.example.com/postgres"))
+	inspect, err = r.InspectImage("mirror.example.com/postgres:latest")
+	a.Nil(err)
+	a.Equal("arm64", inspect.Architecture)
+	a.NotNil(r.TagImage("mysql", "mirror.example.com/mysql"))
+
+	a.Nil(r.RemoveImageByName("redis"))
+	summary, err = r.GetImageByName("redis")
+	a.Nil(err)
+	a.Nil(summary)
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	r.SetStartupLogs("app", "started")
+
+	_, err := r.CreateContainer(dit.ContainerConfig{Name: "app-test", Image: "redis"})
+	a.EqualError(err, "No such image: redis")
+
+	id := newTestContainer(a, r, "app", nil)
+	_, err = r.CreateContainer(dit.ContainerConfig{Name: "app-test", Image: "busybox"})
+	a.NotNil(err)
+	a.Equal("created", r.State("app"))
+
+	container, err := r.GetContainerByName("/app-test")
+	a.Nil(err)
+	a.Equal(id, container.ID)
+
+	a.Nil(r.StartContainer(id))
+	a.Equal("running", r.State("App"))
+	container, err = r.GetContainerByID(id)
+	a.Nil(err)
+	a.Equal("running", container.State)
+
+	inspect, err := r.InspectContainer(id)
+	a.Nil(err)
+	a.True(inspect.State.Running)
+	a.Nil(inspect.State.Health)
+	a.NotEmpty(inspect.NetworkSettings.IPAddress)
+
+	a.Nil(r.WriteErrorLog("app", "warning"))
+	reader, err := r.ContainerLogs(id, false, false, time.Time{})
+	a.Nil(err)
+	stdout, stderr := readLogs(a, reader)
+	a.Equal("started\n", stdout)
+	a.Equal("warning\n", stderr)
+
+	a.Nil(r.StopContainer(id, nil))
+	a.Equal("exited", r.State("app"))
+	a.NotNil(r.WriteLog("app", "stopped"))
+	_, err = r.ExecContainer(id, []string{"true"}, nil, nil)
+	a.NotNil(err)
+
+	a.Nil(r.RemoveContainer(id))
+	a.Equal("", r.State("app"))
+	container, err = r.GetContainerByID(id)
+	a.Nil(err)
+	a.Nil(container)
+	a.NotNil(r.StartContainer(id))
+}
+
+func TestContainerFailures(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	r.Fail(OperationCreate, "app", errors.New("create failed"))
+	_, err := r.CreateContainer(dit.ContainerConfig{Name: "app-test", Image: "busybox", Labels: map[string]string{dit.LabelComponent: "app"}})
+	a.EqualError(err, "create failed")
+	r.Fail(OperationCreate, "app", nil)
+
+	id := newTestContainer(a, r, "app", nil)
+	r.Fail(OperationStart, "app", errors.New("start failed"))
+	a.EqualError(r.StartContainer(id), "start failed")
+	a.Equal("created", r.State("app"))
+	r.Fail(OperationStart, "app", nil)
+
+	a.Nil(r.StartContainer(id))
+	a.Nil(r.OOM("app"))
+	inspect, err := r.InspectContainer(id)
+	a.Nil(err)
+	a.False(inspect.State.Running)
+	a.True(inspect.State.OOMKilled)
+	a.Equal(137, inspect.State.ExitCode)
+
+	a.Nil(r.StartContainer(id))
+	a.Nil(r.Exit("app", 3))
+	inspect, err = r.InspectContainer(id)
+	a.Nil(err)
+	a.False(inspect.State.OOMKilled)
+	a.Equal(3, inspect.State.ExitCode)
+	a.NotNil(r.Exit("app", 3))
+}
+
+func TestContainerHealth(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	id := newTestContainer(a, r, "app", &dit.Healthcheck{Test: []string{"CMD", "true"}})
+	a.Nil(r.StartContainer(id))
+	inspect, err := r.InspectContainer(id)
+	a.Nil(err)
+	a.Equal("healthy", inspect.State.Health.Status)
+
+	r.SetHealth("app", "unhealthy")
+	inspect, err = r.InspectContainer(id)
+	a.Nil(err)
+	a.Equal("unhealthy", inspect.State.Health.Status)
+
+	disabled := newTestContainer(a, r, "db", &dit.Healthcheck{Test: []string{"NONE"}})
+	a.Nil(r.StartContainer(disabled))
+	inspect, err = r.InspectContainer(disabled)
+	a.Nil(err)
+	a.Nil(inspect.State.Health)
+}
+
+func TestExecContainer(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	id := newTestContainer(a, r, "app", nil)
+	a.Nil(r.StartContainer(id))
+
+	exitCode, err := r.ExecContainer(id, []string{"true"}, nil, nil)
+	a.Nil(err)
+	a.Equal(0, exitCode)
+
+	r.HandleExec("app", func(cmd []string, stdout io.Writer, stderr io.Writer) int {
+		fmt.Fprintln(stdout, cmd)
+		return 2
+	})
+	var stdout bytes.Buffer
+	exitCode, err = r.ExecContainer(id, []string{"echo", "hello"}, &stdout, nil)
+	a.Nil(err)
+	a.Equal(2, exitCode)
+	a.Equal("[echo hello]\n", stdout.String())
+
+	r.Fail(OperationExec, "app", errors.New("exec failed"))
+	_, err = r.ExecContainer(id, []string{"true"}, nil, nil)
+	a.EqualError(err, "exec failed")
+}
+
+func TestFollowLogs(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	r.SetStartupLogs("app", "started")
+	id := newTestContainer(a, r, "app", nil)
+	a.Nil(r.StartContainer(id))
+
+	reader, err := r.ContainerLogs(id, true, false, time.Time{})
+	a.Nil(err)
+	a.Nil(r.WriteLog("app", "running"))
+	a.Nil(r.StopContainer(id, nil))
+
+	// the followed logs end when the container stops
+	stdout, _ := readLogs(a, reader)
+	a.Equal("started\nrunning\n", stdout)
+
+	// since skips the logs of the previous run
+	since := time.Now()
+	a.Nil(r.StartContainer(id))
+	reader, err = r.ContainerLogs(id, false, true, since)
+	a.Nil(err)
+	stdout, _ = readLogs(a, reader)
+	a.Regexp(`^\S+ started\n$`, stdout)
+
+	reader, err = r.ContainerLogs(id, true, false, since)
+	a.Nil(err)
+	a.Nil(reader.Close())
+	a.Nil(r.WriteLog("app", "after close"))
+
+	// closing the reader ends the following without further log lines
+	reader, err = r.ContainerLogs(id, true, false, since)
+	a.Nil(err)
+	a.Nil(reader.Close())
+	a.Nil(reader.Close())
+	followers := func() int {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		return len(r.containers[id].followers)
+	}
+	for deadline := time.Now().Add(time.Second); followers() != 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	a.Equal(0, followers())
+}
+
+func TestEvents(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime("busybox")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, _ := r.Events(ctx, dit.LabelEnvironmentID+"=test")
+	other, _ := r.Events(ctx, dit.LabelEnvironmentID+"=other")
+
+	id := newTestContainer(a, r, "app", nil)
+	a.Nil(r.StartContainer(id))
+	a.Nil(r.Exit("app", 1))
+
+	next := func() events.Message {
+		select {
+		case message := <-messages:
+			return message
+		case <-time.After(time.Second):
+			a.Fail("event expected")
+			return events.Message{}
+		}
+	}
+	message := next()
+	a.Equal("start", message.Action)
+	a.Equal(id, message.Actor.ID)
+	a.Equal("app", message.Actor.Attributes[dit.LabelComponent])
+	message = next()
+	a.Equal("die", message.Action)
+	a.Equal("1", message.Actor.Attributes["exitCode"])
+	a.Len(other, 0)
+
+	// the channel is closed when the context is done
+	cancel()
+	select {
+	case _, ok := <-messages:
+		a.False(ok)
+	case <-time.After(time.Second):
+		a.Fail("closed channel expected")
+	}
+}
+
+func TestNetworks(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime()
+	id, err := r.CreateNetwork("test-net", map[string]string{dit.LabelEnvironmentID: "test"})
+	a.Nil(err)
+	_, err = r.CreateNetwork("test-net", nil)
+	a.NotNil(err)
+
+	network, err := r.GetNetworkByName("test-net")
+	a.Nil(err)
+	a.Equal(id, network.ID)
+	a.Equal("test", network.Labels[dit.LabelEnvironmentID])
+
+	a.Nil(r.RemoveNetwork(id))
+	a.NotNil(r.RemoveNetwork(id))
+	network, err = r.GetNetworkByName("test-net")
+	a.Nil(err)
+	a.Nil(network)
+}
+
+func TestVolumes(t *testing.T) {
+	a := assert.New(t)
+
+	r := NewRuntime()
+	a.Nil(r.CreateVolume("data", map[string]string{dit.LabelEnvironmentID: "test"}))
+	// an existing volume is kept
+	a.Nil(r.CreateVolume("data", nil))
+
+	volume, err := r.GetVolumeByName("data")
+	a.Nil(err)
+	a.Equal("data", volume.Name)
+	a.Equal("test", volume.Labels[dit.LabelEnvironmentID])
+
+	volume, err = r.GetVolumeByName("other")
+	a.Nil(err)
+	a.Nil(volume)
+}
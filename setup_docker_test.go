@@ -9,3 +9,10 @@ func TestMain(m *testing.M) {
 	code := m.Run()
 	os.Exit(code)
 }
+
+// skipWithoutDaemon skips the tests which need a docker daemon
+func skipWithoutDaemon(t *testing.T) {
+	if !Available() {
+		t.Skip("Docker daemon is not reachable")
+	}
+}
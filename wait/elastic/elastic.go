@@ -9,6 +9,7 @@ import (
 )
 
 // Options defines Elasticsearch wait parameters.
+// String parameters are templates resolved with the environment values.
 type Options struct {
 	WaitOptions        wait.Options
 	Username, Password string
@@ -39,29 +40,37 @@ func (r *elasticWait) Call(componentName string, resolver dit.ValueResolver) err
 	if err != nil {
 		return err
 	}
-	err = r.pollElastic(componentName, url)
+	username, err := resolver.Resolve(r.username)
+	if err != nil {
+		return err
+	}
+	password, err := resolver.Resolve(r.password)
+	if err != nil {
+		return err
+	}
+	err = r.pollElastic(componentName, url, username, password)
 	if err != nil {
 		return fmt.Errorf("elastic wait: failed to connect to %s %v ", url, err)
 	}
 	return nil
 }
 
-func (r *elasticWait) pollElastic(componentName string, url string) error {
+func (r *elasticWait) pollElastic(componentName string, url string, username string, password string) error {
 
 	logger := r.GetLogger(componentName)
 	logger.Println("Waiting for elastic", url)
 
 	f := func() error {
-		return r.waitForGreenStatus(url)
+		return r.waitForGreenStatus(url, username, password)
 	}
 	return r.Poll(componentName, f)
 }
 
-func (r *elasticWait) waitForGreenStatus(url string) error {
+func (r *elasticWait) waitForGreenStatus(url string, username string, password string) error {
 	clientOptions := make([]v5.ClientOptionFunc, 0)
 	clientOptions = append(clientOptions, v5.SetURL(url))
-	if r.password != "" && r.username != "" {
-		clientOptions = append(clientOptions, v5.SetBasicAuth(r.username, r.password))
+	if password != "" && username != "" {
+		clientOptions = append(clientOptions, v5.SetBasicAuth(username, password))
 	}
 	client, err := v5.NewClient(clientOptions...)
 	if err != nil {
@@ -15,6 +15,7 @@ const (
 )
 
 // Options defines Http wait parameters.
+// String parameters are templates resolved with the environment values.
 type Options struct {
 	WaitOptions wait.Options
 	Method      string
@@ -49,29 +50,33 @@ func (r *httpWait) Call(componentName string, resolver dit.ValueResolver) error
 	if err != nil {
 		return err
 	}
-	err = r.pollHTTP(componentName, url)
+	method, err := resolver.Resolve(r.method)
+	if err != nil {
+		return err
+	}
+	err = r.pollHTTP(componentName, method, url)
 	if err != nil {
 		return fmt.Errorf("http wait: failed to connect to %s %v ", url, err)
 	}
 	return nil
 }
 
-func (r *httpWait) pollHTTP(componentName string, url string) error {
+func (r *httpWait) pollHTTP(componentName string, method string, url string) error {
 
 	logger := r.GetLogger(componentName)
 	logger.Println("Waiting for http", url)
 
 	f := func() error {
-		return r.getRequest(url)
+		return r.getRequest(method, url)
 	}
 	return r.Poll(componentName, f)
 }
 
-func (r *httpWait) getRequest(url string) error {
+func (r *httpWait) getRequest(method string, url string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequest(r.method, url, nil)
+	req, err := http.NewRequest(method, url, nil)
 	if err != nil {
 		return err
 	}
@@ -14,6 +14,7 @@ const (
 )
 
 // Options defines Kafka wait parameters.
+// String parameters are templates resolved with the environment values.
 type Options struct {
 	WaitOptions wait.Options
 	Topic       string
@@ -48,29 +49,33 @@ func (r *kafkaWait) Call(componentName string, resolver dit.ValueResolver) error
 	if err != nil {
 		return err
 	}
-	err = r.pollKafka(componentName, url)
+	topic, err := resolver.Resolve(r.topic)
+	if err != nil {
+		return err
+	}
+	err = r.pollKafka(componentName, url, topic)
 	if err != nil {
 		return fmt.Errorf("kafka wait: failed to connect to %s %v ", url, err)
 	}
 	return nil
 }
 
-func (r *kafkaWait) pollKafka(componentName string, url string) error {
+func (r *kafkaWait) pollKafka(componentName string, url string, topic string) error {
 
 	logger := r.GetLogger(componentName)
 	logger.Println("Waiting for kafka", url)
 
 	f := func() error {
-		partition, err := r.produce(url)
+		partition, err := r.produce(url, topic)
 		if err != nil {
 			return err
 		}
-		return r.consume(url, partition)
+		return r.consume(url, topic, partition)
 	}
 	return r.Poll(componentName, f)
 }
 
-func (r *kafkaWait) produce(brokerAddr string) (int32, error) {
+func (r *kafkaWait) produce(brokerAddr string, topic string) (int32, error) {
 	config := sarama.NewConfig()
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Retry.Max = 1
@@ -84,7 +89,7 @@ func (r *kafkaWait) produce(brokerAddr string) (int32, error) {
 	defer producer.Close()
 
 	msg := &sarama.ProducerMessage{
-		Topic: r.topic,
+		Topic: topic,
 		Value: sarama.StringEncoder("Ping"),
 	}
 
@@ -95,7 +100,7 @@ func (r *kafkaWait) produce(brokerAddr string) (int32, error) {
 	return partition, nil
 }
 
-func (r *kafkaWait) consume(brokerAddr string, partition int32) error {
+func (r *kafkaWait) consume(brokerAddr string, topic string, partition int32) error {
 	config := sarama.NewConfig()
 	config.Consumer.Return.Errors = true
 	config.Consumer.Offsets.Initial = sarama.OffsetOldest
@@ -107,7 +112,7 @@ func (r *kafkaWait) consume(brokerAddr string, partition int32) error {
 	}
 	defer consumer.Close()
 
-	partitionConsumer, err := consumer.ConsumePartition(r.topic, partition, sarama.OffsetOldest)
+	partitionConsumer, err := consumer.ConsumePartition(topic, partition, sarama.OffsetOldest)
 	if err != nil {
 		return err
 	}
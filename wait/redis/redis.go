@@ -9,6 +9,7 @@ import (
 )
 
 // Options defines Redis wait parameters.
+// String parameters are templates resolved with the environment values.
 type Options struct {
 	WaitOptions wait.Options
 	PortName    string
@@ -29,7 +30,11 @@ func NewRedisWait(options Options) *redisWait {
 
 // implements dockerit.Callback
 func (r *redisWait) Call(componentName string, resolver dit.ValueResolver) error {
-	port, err := resolver.Port(componentName, r.portName)
+	portName, err := resolver.Resolve(r.portName)
+	if err != nil {
+		return err
+	}
+	port, err := resolver.Port(componentName, portName)
 	if err != nil {
 		return err
 	}